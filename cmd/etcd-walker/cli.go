@@ -0,0 +1,616 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+
+	"github.com/nexusriot/etcd-walker/pkg/export"
+	"github.com/nexusriot/etcd-walker/pkg/model"
+)
+
+// Exit codes of the headless commands.
+const (
+	exitOK       = 0
+	exitFailure  = 1 // etcd or I/O error
+	exitUsage    = 2 // bad command line
+	exitNotFound = 3 // key or directory does not exist
+	exitConflict = 4 // target exists, import conflict or concurrent change
+)
+
+// usageError marks errors caused by the command line rather than etcd.
+type usageError struct{ msg string }
+
+func (e *usageError) Error() string { return e.msg }
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(c *cli, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"ls", "[--json] [--rev N] [dir]", "list a directory", (*cli).ls},
+		{"tree", "[--json] [--rev N] [dir]", "list a directory recursively", (*cli).tree},
+		{"get", "[--json] [--rev N] key", "print a key's value", (*cli).get},
+		{"put", "key [value|-]", "set a key (value read from stdin if omitted or -)", (*cli).put},
+		{"mkdir", "dir", "create a directory", (*cli).mkdir},
+		{"rm", "[-r] path", "delete a key, or a directory with -r", (*cli).rm},
+		{"mv", "[--force] from to", "rename a key or directory", (*cli).mv},
+		{"export", "[--format F] [-o file] [--rev N] [dir]", "export keys (formats: " + strings.Join(export.Names(), ", ") + ")", (*cli).export},
+		{"import", "[--policy P] [--from P --to P] [--dry-run] [--json] [file|-]", "import a flat JSON export", (*cli).importJSON},
+		{"snapshot", "file", "save a snapshot of the cluster's database to file (v3)", (*cli).snapshot},
+	}
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command [args]]\n\n", os.Args[0])
+	fmt.Fprintf(out, "Without a command the interactive UI is started.\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-8s %s\n           %s\n", cmd.name, cmd.args, cmd.short)
+	}
+	fmt.Fprintf(out, "\nExit codes: 0 ok, 1 error, 2 usage, 3 not found, 4 conflict.\n\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// cli runs one headless command. The model is created lazily so that
+// usage errors are reported without connecting to etcd.
+type cli struct {
+	ctx  context.Context // cancelled by an interrupt
+	opts model.Options
+	m    *model.Model
+	in   io.Reader
+	out  io.Writer
+}
+
+// runCommand executes a subcommand and returns the process exit code.
+func runCommand(opts model.Options, args []string) int {
+	if args[0] == "help" {
+		printUsage()
+		return exitOK
+	}
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "etcd-walker: unknown command %q (see %s -h)\n", args[0], os.Args[0])
+		return exitUsage
+	}
+
+	// Ctrl+C abandons the request in flight instead of killing the process
+	// halfway through writing a file
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	c := &cli{ctx: ctx, opts: opts, in: os.Stdin, out: os.Stdout}
+	defer func() {
+		if c.m != nil {
+			c.m.Close()
+		}
+	}()
+	err := cmd.run(c, args[1:])
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	fmt.Fprintf(os.Stderr, "etcd-walker %s: %v\n", cmd.name, err)
+
+	var uerr *usageError
+	switch {
+	case errors.As(err, &uerr):
+		fmt.Fprintf(os.Stderr, "usage: %s %s %s\n", os.Args[0], cmd.name, cmd.args)
+		return exitUsage
+	case errors.Is(err, model.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, model.ErrTargetExists),
+		errors.Is(err, model.ErrImportConflict),
+		errors.Is(err, model.ErrConflict):
+		return exitConflict
+	}
+	return exitFailure
+}
+
+func (c *cli) model() (*model.Model, error) {
+	if c.m == nil {
+		m, err := model.NewModel(c.ctx, c.opts)
+		if err != nil {
+			return nil, err
+		}
+		c.m = m
+	}
+	return c.m, nil
+}
+
+// readModel connects and pins rev when it is set.
+func (c *cli) readModel(rev int64) (*model.Model, error) {
+	m, err := c.model()
+	if err != nil {
+		return nil, err
+	}
+	if rev != 0 {
+		if err := m.PinRevision(c.ctx, rev); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// parseArgs parses flags placed anywhere among the positional arguments
+// (`ls /app --json`); everything after "--" is positional.
+func parseArgs(fs *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, &usageError{msg: err.Error()}
+		}
+		rest := fs.Args()
+		if consumed := args[:len(args)-len(rest)]; len(consumed) > 0 && consumed[len(consumed)-1] == "--" {
+			pos = append(pos, rest...)
+			break
+		}
+		if len(rest) == 0 {
+			break
+		}
+		pos = append(pos, rest[0])
+		args = rest[1:]
+	}
+	if len(pos) < min || len(pos) > max {
+		return nil, usagef("expected %d to %d arguments, got %d", min, max, len(pos))
+	}
+	return pos, nil
+}
+
+// jsonNode is the --json shape of a key or directory.
+type jsonNode struct {
+	Key            string      `json:"key"`
+	Dir            bool        `json:"dir,omitempty"`
+	Value          *string     `json:"value,omitempty"`
+	CreateRevision int64       `json:"create_revision,omitempty"`
+	ModRevision    int64       `json:"mod_revision,omitempty"`
+	Version        int64       `json:"version,omitempty"`
+	Lease          int64       `json:"lease,omitempty"`
+	CreatedIndex   uint64      `json:"created_index,omitempty"`
+	ModifiedIndex  uint64      `json:"modified_index,omitempty"`
+	TTL            int64       `json:"ttl,omitempty"`
+	Nodes          []*jsonNode `json:"nodes,omitempty"`
+}
+
+func toJSON(n *model.Node) *jsonNode {
+	j := &jsonNode{
+		Key:            n.Name,
+		Dir:            n.IsDir,
+		CreateRevision: n.CreateRevision,
+		ModRevision:    n.ModRevision,
+		Version:        n.Version,
+		Lease:          n.Lease,
+		CreatedIndex:   n.CreatedIndex,
+		ModifiedIndex:  n.ModifiedIndex,
+		TTL:            n.TTL,
+	}
+	if !n.IsDir {
+		v := n.Value
+		j.Value = &v
+	}
+	return j
+}
+
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// lsDir lists dir and tells an empty directory from a missing one.
+func lsDir(ctx context.Context, m *model.Model, dir string) ([]*model.Node, error) {
+	nodes, err := m.Ls(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 && path.Clean("/"+dir) != "/" {
+		n, err := m.Get(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		if !n.IsDir {
+			return nil, fmt.Errorf("not a directory: %s", n.Name)
+		}
+	}
+	return nodes, nil
+}
+
+// withValues fetches the values of keys listed without one (v3 Ls reads
+// keys only).
+func withValues(ctx context.Context, m *model.Model, nodes []*model.Node) error {
+	for i, n := range nodes {
+		full, err := m.Load(ctx, n)
+		if err != nil {
+			return err
+		}
+		nodes[i] = full
+	}
+	return nil
+}
+
+func (c *cli) ls(args []string) error {
+	fs := newFlagSet("ls")
+	asJSON := fs.Bool("json", false, "print JSON")
+	rev := fs.Int64("rev", 0, "read at this revision (v3)")
+	pos, err := parseArgs(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	dir := "/"
+	if len(pos) == 1 {
+		dir = pos[0]
+	}
+	m, err := c.readModel(*rev)
+	if err != nil {
+		return err
+	}
+	nodes, err := lsDir(c.ctx, m, dir)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if err := withValues(c.ctx, m, nodes); err != nil {
+			return err
+		}
+		out := make([]*jsonNode, 0, len(nodes))
+		for _, n := range nodes {
+			out = append(out, toJSON(n))
+		}
+		return c.printJSON(out)
+	}
+	for _, n := range nodes {
+		if n.IsDir {
+			fmt.Fprintln(c.out, n.Name+"/")
+		} else {
+			fmt.Fprintln(c.out, n.Name)
+		}
+	}
+	return nil
+}
+
+func (c *cli) tree(args []string) error {
+	fs := newFlagSet("tree")
+	asJSON := fs.Bool("json", false, "print JSON")
+	rev := fs.Int64("rev", 0, "read at this revision (v3)")
+	pos, err := parseArgs(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	dir := "/"
+	if len(pos) == 1 {
+		dir = pos[0]
+	}
+	m, err := c.readModel(*rev)
+	if err != nil {
+		return err
+	}
+	nodes, err := lsDir(c.ctx, m, dir)
+	if err != nil {
+		return err
+	}
+
+	root := &jsonNode{Key: path.Clean("/" + dir), Dir: true}
+	var walk func(parent *jsonNode, nodes []*model.Node) error
+	walk = func(parent *jsonNode, nodes []*model.Node) error {
+		for _, n := range nodes {
+			j := toJSON(n)
+			parent.Nodes = append(parent.Nodes, j)
+			if !n.IsDir {
+				continue
+			}
+			children, err := m.Ls(c.ctx, n.Name)
+			if err != nil {
+				return err
+			}
+			if err := walk(j, children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(root, nodes); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		// one scan for every value instead of a read per key
+		values, err := m.Export(c.ctx, dir, nil)
+		if err != nil {
+			return err
+		}
+		var fill func(nodes []*jsonNode)
+		fill = func(nodes []*jsonNode) {
+			for _, n := range nodes {
+				if v, ok := values[n.Key]; ok && !n.Dir {
+					n.Value = &v
+				}
+				fill(n.Nodes)
+			}
+		}
+		fill(root.Nodes)
+		return c.printJSON(root)
+	}
+	fmt.Fprintln(c.out, root.Key)
+	var print func(nodes []*jsonNode, indent string)
+	print = func(nodes []*jsonNode, indent string) {
+		for _, n := range nodes {
+			name := path.Base(n.Key)
+			if n.Dir {
+				name += "/"
+			}
+			fmt.Fprintln(c.out, indent+name)
+			print(n.Nodes, indent+"  ")
+		}
+	}
+	print(root.Nodes, "  ")
+	return nil
+}
+
+func (c *cli) get(args []string) error {
+	fs := newFlagSet("get")
+	asJSON := fs.Bool("json", false, "print JSON with metadata")
+	rev := fs.Int64("rev", 0, "read at this revision (v3)")
+	pos, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	m, err := c.readModel(*rev)
+	if err != nil {
+		return err
+	}
+	n, err := m.Get(c.ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return c.printJSON(toJSON(n))
+	}
+	if n.IsDir {
+		return fmt.Errorf("is a directory: %s", n.Name)
+	}
+	fmt.Fprintln(c.out, n.Value)
+	return nil
+}
+
+func (c *cli) put(args []string) error {
+	pos, err := parseArgs(newFlagSet("put"), args, 1, 2)
+	if err != nil {
+		return err
+	}
+	var value string
+	if len(pos) == 2 && pos[1] != "-" {
+		value = pos[1]
+	} else {
+		raw, err := io.ReadAll(c.in)
+		if err != nil {
+			return err
+		}
+		value = string(raw)
+	}
+	m, err := c.model()
+	if err != nil {
+		return err
+	}
+	return m.Set(c.ctx, pos[0], value)
+}
+
+func (c *cli) mkdir(args []string) error {
+	pos, err := parseArgs(newFlagSet("mkdir"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	m, err := c.model()
+	if err != nil {
+		return err
+	}
+	return m.MkDir(c.ctx, pos[0])
+}
+
+func (c *cli) rm(args []string) error {
+	fs := newFlagSet("rm")
+	recursive := fs.Bool("r", false, "delete a directory and everything below it")
+	pos, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	m, err := c.model()
+	if err != nil {
+		return err
+	}
+	n, err := m.Get(c.ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	if !n.IsDir {
+		return m.Del(c.ctx, n.Name)
+	}
+	if !*recursive {
+		return fmt.Errorf("%s is a directory (use -r)", n.Name)
+	}
+	return m.DelDir(c.ctx, n.Name, nil)
+}
+
+func (c *cli) mv(args []string) error {
+	fs := newFlagSet("mv")
+	force := fs.Bool("force", false, "overwrite existing target keys")
+	pos, err := parseArgs(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	m, err := c.model()
+	if err != nil {
+		return err
+	}
+	n, err := m.Get(c.ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	p, err := m.PlanMove(c.ctx, n.Name, pos[1], n.IsDir)
+	if err != nil {
+		return err
+	}
+	return m.Move(c.ctx, p, *force, nil)
+}
+
+func (c *cli) export(args []string) error {
+	fs := newFlagSet("export")
+	format := fs.String("format", "json", "output format: "+strings.Join(export.Names(), ", "))
+	output := fs.String("o", "", "write to this file instead of stdout")
+	rev := fs.Int64("rev", 0, "read at this revision (v3)")
+	pos, err := parseArgs(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	exp, err := export.Get(*format)
+	if err != nil {
+		return &usageError{msg: err.Error()}
+	}
+	dir := "/"
+	if len(pos) == 1 {
+		dir = pos[0]
+	}
+	m, err := c.readModel(*rev)
+	if err != nil {
+		return err
+	}
+	data, err := m.Export(c.ctx, dir, nil)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	opts := export.Options{Root: path.Clean("/" + dir), Protocol: m.ProtocolVersion()}
+	if err := exp.Write(&buf, data, opts); err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = c.out.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(*output, buf.Bytes(), 0o600)
+}
+
+func (c *cli) snapshot(args []string) error {
+	pos, err := parseArgs(newFlagSet("snapshot"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	m, err := c.model()
+	if err != nil {
+		return err
+	}
+	_, err = m.SaveSnapshot(c.ctx, pos[0], nil)
+	return err
+}
+
+func (c *cli) importJSON(args []string) error {
+	fs := newFlagSet("import")
+	policy := fs.String("policy", model.ImportSkip.String(), "existing keys with a different value: skip, overwrite or fail")
+	from := fs.String("from", "", "only import keys under this prefix ...")
+	to := fs.String("to", "", "... and write them under this one instead")
+	dryRun := fs.Bool("dry-run", false, "only report what would change")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	pos, err := parseArgs(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	opts := model.ImportOptions{FromPrefix: *from, ToPrefix: *to, DryRun: *dryRun}
+	switch *policy {
+	case model.ImportSkip.String():
+		opts.Policy = model.ImportSkip
+	case model.ImportOverwrite.String():
+		opts.Policy = model.ImportOverwrite
+	case model.ImportFail.String():
+		opts.Policy = model.ImportFail
+	default:
+		return usagef("unknown policy %q", *policy)
+	}
+	if (*from == "") != (*to == "") {
+		return usagef("--from and --to must be given together")
+	}
+
+	var raw []byte
+	if len(pos) == 0 || pos[0] == "-" {
+		raw, err = io.ReadAll(c.in)
+	} else {
+		raw, err = os.ReadFile(pos[0])
+	}
+	if err != nil {
+		return err
+	}
+	var data map[string]string
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("expected a flat {\"key\": \"value\"} object: %w", err)
+	}
+
+	m, err := c.model()
+	if err != nil {
+		return err
+	}
+	rep, err := m.Import(c.ctx, data, opts, nil)
+	if rep != nil {
+		if *asJSON {
+			if perr := c.printJSON(struct {
+				Create    []string `json:"create"`
+				Update    []string `json:"update"`
+				Unchanged []string `json:"unchanged"`
+				Skipped   []string `json:"skipped"`
+				Conflicts []string `json:"conflicts"`
+				DryRun    bool     `json:"dry_run"`
+			}{rep.Create, rep.Update, rep.Unchanged, rep.Skipped, rep.Conflicts, opts.DryRun}); perr != nil && err == nil {
+				err = perr
+			}
+		} else {
+			for _, k := range rep.Create {
+				fmt.Fprintln(c.out, "+ "+k)
+			}
+			for _, k := range rep.Update {
+				fmt.Fprintln(c.out, "~ "+k)
+			}
+			for _, k := range rep.Skipped {
+				fmt.Fprintln(c.out, "! "+k)
+			}
+			for _, k := range rep.Conflicts {
+				fmt.Fprintln(c.out, "x "+k)
+			}
+			fmt.Fprintf(c.out, "create %d, update %d, unchanged %d, skipped %d, conflicts %d\n",
+				len(rep.Create), len(rep.Update), len(rep.Unchanged), len(rep.Skipped), len(rep.Conflicts))
+		}
+	}
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"os"
 	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
@@ -40,10 +41,22 @@ func (f *boolFlag) Set(s string) error {
 	return nil
 }
 
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func main() {
 	var (
 		hostFlag          = &stringFlag{value: ""}
 		portFlag          = &stringFlag{value: ""}
+		endpointsFlag     = &stringFlag{value: ""}
 		protocolFlag      = &stringFlag{value: ""}
 		debugFlag         = &boolFlag{value: false}
 		usernameFlag      = &stringFlag{value: ""}
@@ -54,11 +67,15 @@ func main() {
 		tlsKeyFlag        = &stringFlag{value: ""}
 		tlsSkipVerifyFlag = &boolFlag{value: false}
 		timeoutFlag       = &stringFlag{value: ""}
+		readOnlyFlag      = &boolFlag{value: false}
+		profileFlag       = &stringFlag{value: ""}
+		snapshotFlag      = &stringFlag{value: ""}
 		configPath        = flag.String("config", config.DefaultPath, "config file, optional")
 	)
 
 	flag.Var(hostFlag, "host", "etcd host (e.g. 127.0.0.1)")
 	flag.Var(portFlag, "port", "etcd port (e.g. 2379)")
+	flag.Var(endpointsFlag, "endpoints", "comma-separated etcd endpoints, replaces host/port (e.g. https://a:2379,https://b:2379)")
 	flag.Var(protocolFlag, "protocol", "etcd protocol: v2, v3, auto (default: auto)")
 	flag.Var(debugFlag, "debug", "enable debug logging (true/false)")
 	flag.Var(usernameFlag, "username", "etcd auth username")
@@ -69,91 +86,91 @@ func main() {
 	flag.Var(tlsKeyFlag, "tls-key", "path to client key file for mutual TLS")
 	flag.Var(tlsSkipVerifyFlag, "tls-skip-verify", "skip TLS server certificate verification (insecure)")
 	flag.Var(timeoutFlag, "timeout", "etcd operation timeout in seconds (default: 5)")
+	flag.Var(readOnlyFlag, "read-only", "refuse all writes to etcd (true/false)")
+	flag.Var(profileFlag, "profile", "connection profile from the config file")
+	flag.Var(snapshotFlag, "snapshot", "browse an etcd v3 snapshot or member db file read-only instead of connecting")
+	flag.Usage = printUsage
 	flag.Parse()
 
-	// Hardcoded defaults
-	host := "127.0.0.1"
-	port := "2379"
-	protocol := "auto"
-	username := ""
-	password := ""
-	debug := false
-	tlsEnabled := false
-	tlsCAFile := ""
-	tlsCertFile := ""
-	tlsKeyFile := ""
-	tlsSkipVerify := false
-	timeoutSeconds := 0
-
 	// Always load config first as a base; CLI flags override individual fields.
 	cfg, err := config.Load(*configPath)
 	if err != nil {
 		log.WithError(err).Warn("failed to load config, falling back to defaults")
 	}
+
+	debug := false
+	profile := ""
+	var conn config.Connection
 	if cfg != nil {
-		if cfg.Host != "" {
-			host = cfg.Host
-		}
-		if cfg.Port != "" {
-			port = cfg.Port
-		}
-		if cfg.Protocol != "" {
-			protocol = cfg.Protocol
-		}
-		if cfg.Username != "" {
-			username = cfg.Username
+		debug = cfg.Debug
+		profile = cfg.Profile
+	}
+	if profileFlag.set {
+		profile = profileFlag.value
+	}
+	if cfg != nil {
+		if conn, err = cfg.Resolve(profile); err != nil {
+			log.WithError(err).Fatal("invalid profile")
 		}
-		if cfg.Password != "" {
-			password = cfg.Password
+		if profile == "" && len(cfg.Profiles) > 0 {
+			profile = config.DefaultProfile
 		}
-		debug = cfg.Debug
-		tlsEnabled = cfg.TLSEnabled
-		tlsCAFile = cfg.TLSCAFile
-		tlsCertFile = cfg.TLSCertFile
-		tlsKeyFile = cfg.TLSKeyFile
-		tlsSkipVerify = cfg.TLSSkipVerify
-		timeoutSeconds = cfg.TimeoutSeconds
+	} else if profile != "" {
+		log.Fatalf("profile %q requested but no config file was loaded", profile)
 	}
+	opts := options(conn)
 
 	// CLI flags take precedence over config file values.
 	if hostFlag.set && hostFlag.value != "" {
-		host = hostFlag.value
+		opts.Host = hostFlag.value
 	}
 	if portFlag.set && portFlag.value != "" {
-		port = portFlag.value
+		opts.Port = portFlag.value
+	}
+	if endpointsFlag.set {
+		opts.Endpoints = splitList(endpointsFlag.value)
+	} else if hostFlag.set || portFlag.set {
+		// an explicit -host/-port means "this one node", not the config's list
+		opts.Endpoints = nil
 	}
 	if protocolFlag.set && protocolFlag.value != "" {
-		protocol = protocolFlag.value
+		opts.Protocol = protocolFlag.value
 	}
 	if usernameFlag.set {
-		username = usernameFlag.value
+		opts.Username = usernameFlag.value
 	}
 	if passwordFlag.set {
-		password = passwordFlag.value
+		opts.Password = passwordFlag.value
 	}
 	if debugFlag.set {
 		debug = debugFlag.value
 	}
 	if tlsFlag.set {
-		tlsEnabled = tlsFlag.value
+		opts.TLSEnabled = tlsFlag.value
 	}
 	if tlsCAFlag.set {
-		tlsCAFile = tlsCAFlag.value
+		opts.TLSCAFile = tlsCAFlag.value
 	}
 	if tlsCertFlag.set {
-		tlsCertFile = tlsCertFlag.value
+		opts.TLSCertFile = tlsCertFlag.value
 	}
 	if tlsKeyFlag.set {
-		tlsKeyFile = tlsKeyFlag.value
+		opts.TLSKeyFile = tlsKeyFlag.value
 	}
 	if tlsSkipVerifyFlag.set {
-		tlsSkipVerify = tlsSkipVerifyFlag.value
+		opts.TLSSkipVerify = tlsSkipVerifyFlag.value
 	}
 	if timeoutFlag.set && timeoutFlag.value != "" {
 		if v, err := strconv.Atoi(timeoutFlag.value); err == nil && v > 0 {
-			timeoutSeconds = v
+			opts.TimeoutSeconds = v
 		}
 	}
+	if readOnlyFlag.set {
+		opts.ReadOnly = readOnlyFlag.value
+	}
+	if snapshotFlag.set {
+		opts.Snapshot = snapshotFlag.value
+	}
 	log.SetOutput(os.Stderr)
 
 	if debug {
@@ -161,31 +178,80 @@ func main() {
 	}
 
 	log.WithFields(log.Fields{
-		"host":        host,
-		"port":        port,
-		"protocol":    protocol,
+		"profile":     profile,
+		"host":        opts.Host,
+		"port":        opts.Port,
+		"endpoints":   opts.Endpoints,
+		"protocol":    opts.Protocol,
 		"debug":       debug,
-		"tls":         tlsEnabled,
-		"timeout_sec": timeoutSeconds,
+		"tls":         opts.TLSEnabled,
+		"timeout_sec": opts.TimeoutSeconds,
+		"read_only":   opts.ReadOnly,
+		"snapshot":    opts.Snapshot,
 		"config":      *configPath,
 	}).Debug("Starting etcd-walker")
 
-	opts := model.Options{
-		Host:           host,
-		Port:           port,
-		Protocol:       protocol,
-		Username:       username,
-		Password:       password,
-		TLSEnabled:     tlsEnabled,
-		TLSCAFile:      tlsCAFile,
-		TLSCertFile:    tlsCertFile,
-		TLSKeyFile:     tlsKeyFile,
-		TLSSkipVerify:  tlsSkipVerify,
-		TimeoutSeconds: timeoutSeconds,
-	}
-	ctrl := controller.NewController(opts, debug)
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(opts, flag.Args()))
+	}
+
+	// Every profile can be switched to from the UI; the active one keeps
+	// the CLI overrides.
+	current := controller.Profile{Name: profile, Options: opts, Color: conn.Color}
+	var profiles []controller.Profile
+	if cfg != nil {
+		for _, name := range cfg.ProfileNames() {
+			if name == profile {
+				profiles = append(profiles, current)
+				continue
+			}
+			p, _ := cfg.Resolve(name)
+			o := options(p)
+			if p.ReadOnly == nil && readOnlyFlag.set {
+				// -read-only covers the profiles that do not set read_only
+				o.ReadOnly = readOnlyFlag.value
+			}
+			profiles = append(profiles, controller.Profile{Name: name, Options: o, Color: p.Color})
+		}
+	}
+
+	ctrl := controller.NewController(current, profiles, debug)
 	if err := ctrl.Run(); err != nil {
 		log.WithError(err).Error("etcd-walker exited with error")
 		os.Exit(1)
 	}
 }
+
+// options applies a config connection on top of the hard-coded defaults.
+func options(conn config.Connection) model.Options {
+	opts := model.Options{
+		Host:     "127.0.0.1",
+		Port:     "2379",
+		Protocol: "auto",
+	}
+	if conn.Host != "" {
+		opts.Host = conn.Host
+	}
+	if conn.Port != "" {
+		opts.Port = conn.Port
+	}
+	if conn.Protocol != "" {
+		opts.Protocol = conn.Protocol
+	}
+	opts.Endpoints = conn.Endpoints
+	opts.Username = conn.Username
+	opts.Password = conn.Password
+	opts.TLSEnabled = conn.TLSEnabled
+	opts.TLSCAFile = conn.TLSCAFile
+	opts.TLSCertFile = conn.TLSCertFile
+	opts.TLSKeyFile = conn.TLSKeyFile
+	opts.TLSSkipVerify = conn.TLSSkipVerify
+	opts.TimeoutSeconds = conn.TimeoutSeconds
+	opts.ScanTimeoutSeconds = conn.ScanTimeoutSeconds
+	opts.BulkTimeoutSeconds = conn.BulkTimeoutSeconds
+	opts.MaintenanceTimeoutSeconds = conn.MaintenanceTimeoutSeconds
+	opts.MaxTxnOps = conn.MaxTxnOps
+	opts.ReadOnly = conn.ReadOnly != nil && *conn.ReadOnly
+	opts.Snapshot = conn.Snapshot
+	return opts
+}
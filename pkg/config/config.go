@@ -5,22 +5,30 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 )
 
 const (
 	// DefaultPath is where we try to read config from.
 	DefaultPath = "/etc/etcd-walker/config.json"
+
+	// DefaultProfile names the top-level connection among the profiles,
+	// unless the file defines a profile of that name itself.
+	DefaultProfile = "default"
 )
 
-// Config describes what can be set in JSON config.
-type Config struct {
+// Connection describes how to reach one cluster. The top level of the
+// config file is a Connection, and so is every entry of "profiles".
+type Connection struct {
 	Host     string `json:"host"`
 	Port     string `json:"port"`
 	Protocol string `json:"protocol"` // "v2", "v3", "auto"
-	Debug    bool   `json:"debug"`
 	Username string `json:"username"`
 	Password string `json:"password"`
 
+	// Endpoints (URLs or host:port) replace Host/Port when set
+	Endpoints []string `json:"endpoints"`
+
 	// TLS options (v3 only)
 	TLSEnabled    bool   `json:"tls_enabled"`
 	TLSCAFile     string `json:"tls_ca_file"`
@@ -28,8 +36,74 @@ type Config struct {
 	TLSKeyFile    string `json:"tls_key_file"`
 	TLSSkipVerify bool   `json:"tls_skip_verify"`
 
-	// TimeoutSeconds for etcd operations (0 = default 5s)
+	// TimeoutSeconds for a single etcd request (0 = default 5s)
 	TimeoutSeconds int `json:"timeout_seconds"`
+	// ScanTimeoutSeconds for reading or deleting a whole subtree: export,
+	// history, leases, hashes (0 = 10x timeout_seconds, at least 30s)
+	ScanTimeoutSeconds int `json:"scan_timeout_seconds"`
+	// BulkTimeoutSeconds for imports and each batch of a move or subtree
+	// delete (0 = 4x timeout_seconds, at least 20s)
+	BulkTimeoutSeconds int `json:"bulk_timeout_seconds"`
+	// MaintenanceTimeoutSeconds for compaction and defragmentation
+	// (0 = 5 minutes)
+	MaintenanceTimeoutSeconds int `json:"maintenance_timeout_seconds"`
+
+	// MaxTxnOps must not exceed the server's --max-txn-ops (0 = default 128)
+	MaxTxnOps int `json:"max_txn_ops"`
+
+	// ReadOnly refuses every write (create, edit, rename, delete, import).
+	// Unset, a profile switched to at runtime follows -read-only.
+	ReadOnly *bool `json:"read_only"`
+
+	// Snapshot is an etcd v3 database file to browse read-only instead of
+	// connecting to a cluster
+	Snapshot string `json:"snapshot"`
+
+	// Color of the header text, a tcell color name or #rrggbb
+	Color string `json:"color"`
+}
+
+// Config describes what can be set in JSON config.
+type Config struct {
+	Connection
+	Debug bool `json:"debug"`
+
+	// Profile names the entry of Profiles used when no --profile is given
+	Profile string `json:"profile"`
+	// Profiles are named connections (dev, staging, prod, ...). A profile
+	// replaces the top-level connection settings as a whole.
+	Profiles map[string]Connection `json:"profiles"`
+}
+
+// ProfileNames returns the profile names in lexical order. When there are
+// profiles, the top-level connection is listed too, as DefaultProfile.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles)+1)
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	if _, ok := c.Profiles[DefaultProfile]; !ok && len(names) > 0 {
+		names = append(names, DefaultProfile)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the connection of the named profile, or the top-level
+// connection when name is empty or DefaultProfile (unless a profile has
+// that name).
+func (c *Config) Resolve(name string) (Connection, error) {
+	if name == "" {
+		return c.Connection, nil
+	}
+	if _, ok := c.Profiles[name]; !ok && name == DefaultProfile {
+		return c.Connection, nil
+	}
+	conn, ok := c.Profiles[name]
+	if !ok {
+		return Connection{}, fmt.Errorf("unknown profile %q", name)
+	}
+	return conn, nil
 }
 
 // Load tries to read and unmarshal config from the given path.
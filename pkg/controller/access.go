@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/nexusriot/etcd-walker/pkg/model"
+)
+
+// accessMark is appended to list entries the user cannot fully use.
+func accessMark(a model.AccessLevel) string {
+	switch a {
+	case model.AccessReadOnly:
+		return " [gray](read-only)[-]"
+	case model.AccessPartial:
+		return " [gray](partial)[-]"
+	case model.AccessNone:
+		return " [red](forbidden)[-]"
+	}
+	return ""
+}
+
+// writeDenied reports whether RBAC keeps the user from changing n and
+// tells the user so; the server would refuse the write anyway.
+func (c *Controller) writeDenied(n *model.Node) bool {
+	a := c.model.AccessOf(n.Name, n.IsDir)
+	if a == model.AccessFull {
+		return false
+	}
+	c.error("Permission denied", fmt.Errorf("%w: %s is %s for %s", model.ErrPermissionDenied, n.Name, a, c.opts.Username), false)
+	return true
+}
+
+// showDenied replaces the listing of a directory the user may not read
+// with an explanation in the details pane; [..] still leads back up.
+func (c *Controller) showDenied(err error) {
+	c.cancelDetails()
+	c.view.Details.Clear()
+	fmt.Fprintf(c.view.Details, "[red::b]Permission denied[-::-]\n\n%s\n", err)
+	if c.opts.Username != "" {
+		fmt.Fprintf(c.view.Details, "\nUser [::b]%s[::-] has no read access here.\n", c.opts.Username)
+	}
+}
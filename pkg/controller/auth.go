@@ -0,0 +1,528 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nexusriot/etcd-walker/pkg/model"
+	"github.com/nexusriot/etcd-walker/pkg/view"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+)
+
+// authBrowser is the state of the open auth view (Ctrl+U).
+type authBrowser struct {
+	av      *view.AuthView
+	users   []model.User
+	roles   []model.Role
+	enabled bool
+	// roleItems is true while Items lists the permissions of the selected
+	// role, false while it lists the roles of the selected user.
+	roleItems bool
+}
+
+func (ab *authBrowser) selectedUser() *model.User {
+	i := ab.av.Users.GetCurrentItem()
+	if i < 0 || i >= len(ab.users) {
+		return nil
+	}
+	return &ab.users[i]
+}
+
+func (ab *authBrowser) selectedRole() *model.Role {
+	i := ab.av.Roles.GetCurrentItem()
+	if i < 0 || i >= len(ab.roles) {
+		return nil
+	}
+	return &ab.roles[i]
+}
+
+// manageAuth opens the users / roles / permissions view (v3).
+func (c *Controller) manageAuth() *tcell.EventKey {
+	ab := &authBrowser{av: c.view.NewAuthView()}
+	if !c.loadAuth(ab) {
+		return nil
+	}
+
+	ab.av.Users.SetChangedFunc(func(int, string, string, rune) { c.showUserRoles(ab) })
+	ab.av.Roles.SetChangedFunc(func(int, string, string, rune) { c.showRolePerms(ab) })
+	ab.av.Items.SetSelectedFunc(func(int, string, string, rune) {
+		if ab.roleItems {
+			c.editPermission(ab, ab.selectedRole(), ab.av.Items.GetCurrentItem())
+		}
+	})
+
+	ab.av.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyEsc:
+			c.view.Pages.RemovePage("auth")
+			return nil
+		case tcell.KeyTab:
+			c.cycleAuthFocus(ab)
+			return nil
+		case tcell.KeyRune:
+		default:
+			return ev
+		}
+
+		switch ev.Rune() {
+		case 'r':
+			c.loadAuth(ab)
+			return nil
+		case 't':
+			c.toggleAuth(ab)
+			return nil
+		}
+		switch {
+		case ab.av.Users.HasFocus():
+			switch ev.Rune() {
+			case 'n':
+				c.addUser(ab)
+			case 'p':
+				c.changePassword(ab)
+			case 'g':
+				c.grantRole(ab)
+			case 'x':
+				c.deleteUser(ab)
+			default:
+				return ev
+			}
+		case ab.av.Roles.HasFocus():
+			switch ev.Rune() {
+			case 'n':
+				c.addRole(ab)
+			case 'g':
+				c.editPermission(ab, ab.selectedRole(), -1)
+			case 'x':
+				c.deleteRole(ab)
+			default:
+				return ev
+			}
+		case ab.av.Items.HasFocus():
+			switch {
+			case ev.Rune() == 'x' && ab.roleItems:
+				c.revokePermission(ab)
+			case ev.Rune() == 'x':
+				c.revokeRole(ab)
+			case ev.Rune() == 'g' && ab.roleItems:
+				c.editPermission(ab, ab.selectedRole(), -1)
+			case ev.Rune() == 'e' && ab.roleItems:
+				c.editPermission(ab, ab.selectedRole(), ab.av.Items.GetCurrentItem())
+			default:
+				return ev
+			}
+		default:
+			return ev
+		}
+		return nil
+	})
+
+	c.view.Pages.AddPage("auth", ab.av, true, true)
+	return nil
+}
+
+// cycleAuthFocus moves focus Users → Roles → Items and shows what the
+// newly focused list refers to.
+func (c *Controller) cycleAuthFocus(ab *authBrowser) {
+	switch {
+	case ab.av.Users.HasFocus():
+		c.view.App.SetFocus(ab.av.Roles)
+		c.showRolePerms(ab)
+	case ab.av.Roles.HasFocus():
+		c.view.App.SetFocus(ab.av.Items)
+	default:
+		c.view.App.SetFocus(ab.av.Users)
+		c.showUserRoles(ab)
+	}
+}
+
+// loadAuth (re)reads users, roles and the auth state, keeping the
+// selection on the same names.
+func (c *Controller) loadAuth(ab *authBrowser) bool {
+	var keepUser, keepRole string
+	roleItems := ab.roleItems // filling the lists below fires their changed funcs
+	if u := ab.selectedUser(); u != nil {
+		keepUser = u.Name
+	}
+	if r := ab.selectedRole(); r != nil {
+		keepRole = r.Name
+	}
+
+	enabled, err := c.model.AuthEnabled(c.ctx)
+	if err != nil {
+		c.error("Cannot read auth status", err, false)
+		return false
+	}
+	users, err := c.model.Users(c.ctx)
+	if err != nil {
+		c.error("Cannot list users", err, false)
+		return false
+	}
+	roles, err := c.model.Roles(c.ctx)
+	if err != nil {
+		c.error("Cannot list roles", err, false)
+		return false
+	}
+	ab.enabled, ab.users, ab.roles = enabled, users, roles
+
+	state := "[red]OFF[-]"
+	if enabled {
+		state = "[green]ON[-]"
+	}
+	ab.av.SetTitle(fmt.Sprintf(" Auth %s  [t]Enable/disable [r]Reload [Tab]Switch [Esc]Close ", state))
+
+	ab.av.Users.Clear()
+	for i, u := range users {
+		ab.av.Users.AddItem(tview.Escape(u.Name), "", 0, nil)
+		if u.Name == keepUser {
+			ab.av.Users.SetCurrentItem(i)
+		}
+	}
+	ab.av.Roles.Clear()
+	for i, r := range roles {
+		ab.av.Roles.AddItem(tview.Escape(fmt.Sprintf("%s  (%d permissions)", r.Name, len(r.Perms))), "", 0, nil)
+		if r.Name == keepRole {
+			ab.av.Roles.SetCurrentItem(i)
+		}
+	}
+	if roleItems {
+		c.showRolePerms(ab)
+	} else {
+		c.showUserRoles(ab)
+	}
+	return true
+}
+
+func (c *Controller) showUserRoles(ab *authBrowser) {
+	ab.roleItems = false
+	ab.av.Items.Clear()
+	u := ab.selectedUser()
+	if u == nil {
+		ab.av.Items.SetTitle(" Roles ")
+		return
+	}
+	ab.av.Items.SetTitle(fmt.Sprintf(" Roles of %s  [x]Revoke ", tview.Escape(u.Name)))
+	for _, r := range u.Roles {
+		ab.av.Items.AddItem(tview.Escape(r), "", 0, nil)
+	}
+}
+
+func (c *Controller) showRolePerms(ab *authBrowser) {
+	ab.roleItems = true
+	ab.av.Items.Clear()
+	r := ab.selectedRole()
+	if r == nil {
+		ab.av.Items.SetTitle(" Permissions ")
+		return
+	}
+	ab.av.Items.SetTitle(fmt.Sprintf(" Permissions of %s  [g]Grant [e]Edit [x]Revoke ", tview.Escape(r.Name)))
+	for _, p := range r.Perms {
+		ab.av.Items.AddItem(tview.Escape(p.String()), "", 0, nil)
+	}
+}
+
+// toggleAuth enables or disables authentication after two confirmations.
+func (c *Controller) toggleAuth(ab *authBrowser) {
+	if c.readOnly() {
+		return
+	}
+	endpoint := c.model.Endpoint()
+	first := "Disable authentication?\nEvery client will get full access to every key."
+	second := fmt.Sprintf("Really disable authentication on %s?", endpoint)
+	if !ab.enabled {
+		first = "Enable authentication?\nEvery client will need credentials."
+		root := false
+		for _, u := range ab.users {
+			root = root || u.Name == "root"
+		}
+		if !root {
+			first += "\nThere is no root user yet; etcd will refuse."
+		}
+		if c.opts.Username == "" {
+			first += "\nThis session has no username and will lose access."
+		}
+		second = fmt.Sprintf("Really enable authentication on %s?", endpoint)
+	}
+
+	c.confirm(first, 60, 10, func() {
+		c.confirm(second, 60, 8, func() {
+			var err error
+			if ab.enabled {
+				err = c.model.DisableAuth(c.ctx)
+			} else {
+				err = c.model.EnableAuth(c.ctx)
+			}
+			if err != nil {
+				c.error("Cannot change auth", err, false)
+				return
+			}
+			log.Debugf("Auth enabled: %t", !ab.enabled)
+			c.updateHeader()
+			c.loadAuth(ab)
+		})
+	})
+}
+
+// confirm asks an ok/cancel question on the "modal" page and runs ok.
+func (c *Controller) confirm(text string, w, h int, ok func()) {
+	q := c.view.NewConfirmQ(text)
+	q.SetDoneFunc(func(_ int, buttonLabel string) {
+		c.view.Pages.RemovePage("modal")
+		if buttonLabel == "ok" {
+			ok()
+		}
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(q, w, h), true, true)
+}
+
+func (c *Controller) addUser(ab *authBrowser) {
+	if c.readOnly() {
+		return
+	}
+	form := c.view.NewUserForm("")
+	form.AddButton("Save", func() {
+		name := strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+		pass := form.GetFormItem(1).(*tview.InputField).GetText()
+		again := form.GetFormItem(2).(*tview.InputField).GetText()
+		c.view.Pages.RemovePage("modal")
+		if name == "" {
+			c.error("Invalid user", fmt.Errorf("name must not be empty"), false)
+			return
+		}
+		if pass != again {
+			c.error("Invalid password", fmt.Errorf("passwords do not match"), false)
+			return
+		}
+		if err := c.model.AddUser(c.ctx, name, pass); err != nil {
+			c.error("Cannot add user", err, false)
+			return
+		}
+		c.loadAuth(ab)
+	})
+	c.addCancel(form)
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(form, 50, 11), true, true)
+}
+
+func (c *Controller) changePassword(ab *authBrowser) {
+	u := ab.selectedUser()
+	if u == nil || c.readOnly() {
+		return
+	}
+	name := u.Name
+	form := c.view.NewUserForm(name)
+	form.AddButton("Save", func() {
+		pass := form.GetFormItem(0).(*tview.InputField).GetText()
+		again := form.GetFormItem(1).(*tview.InputField).GetText()
+		c.view.Pages.RemovePage("modal")
+		if pass != again {
+			c.error("Invalid password", fmt.Errorf("passwords do not match"), false)
+			return
+		}
+		if err := c.model.ChangePassword(c.ctx, name, pass); err != nil {
+			c.error("Cannot change password", err, false)
+			return
+		}
+		c.info("Password changed", name)
+	})
+	c.addCancel(form)
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(form, 50, 9), true, true)
+}
+
+// addCancel adds a Cancel button and Esc handling to a "modal" form.
+func (c *Controller) addCancel(form *tview.Form) {
+	form.AddButton("Cancel", func() {
+		c.view.Pages.RemovePage("modal")
+	})
+	form.SetCancelFunc(func() {
+		c.view.Pages.RemovePage("modal")
+	})
+}
+
+func (c *Controller) deleteUser(ab *authBrowser) {
+	u := ab.selectedUser()
+	if u == nil || c.readOnly() {
+		return
+	}
+	name := u.Name
+	c.confirm(fmt.Sprintf("Delete user %s?", name), 60, 8, func() {
+		if err := c.model.DeleteUser(c.ctx, name); err != nil {
+			c.error("Cannot delete user", err, false)
+			return
+		}
+		c.loadAuth(ab)
+	})
+}
+
+// grantRole picks one of the roles the selected user does not have yet.
+func (c *Controller) grantRole(ab *authBrowser) {
+	u := ab.selectedUser()
+	if u == nil || c.readOnly() {
+		return
+	}
+	name := u.Name
+	var choices []string
+	for _, r := range ab.roles {
+		if !slices.Contains(u.Roles, r.Name) {
+			choices = append(choices, r.Name)
+		}
+	}
+	if len(choices) == 0 {
+		c.info("Nothing to grant", fmt.Sprintf("%s already has every role", name))
+		return
+	}
+	picker := c.view.NewPicker(fmt.Sprintf("Grant role to %s", tview.Escape(name)), choices)
+	picker.SetSelectedFunc(func(i int, _, _ string, _ rune) {
+		c.view.Pages.RemovePage("modal")
+		if err := c.model.GrantRole(c.ctx, name, choices[i]); err != nil {
+			c.error("Cannot grant role", err, false)
+			return
+		}
+		c.loadAuth(ab)
+	})
+	picker.SetDoneFunc(func() {
+		c.view.Pages.RemovePage("modal")
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(picker, 40, min(len(choices)+2, 20)), true, true)
+}
+
+func (c *Controller) revokeRole(ab *authBrowser) {
+	u := ab.selectedUser()
+	i := ab.av.Items.GetCurrentItem()
+	if u == nil || i < 0 || i >= len(u.Roles) || c.readOnly() {
+		return
+	}
+	name, role := u.Name, u.Roles[i]
+	c.confirm(fmt.Sprintf("Revoke role %s from %s?", role, name), 60, 8, func() {
+		if err := c.model.RevokeRole(c.ctx, name, role); err != nil {
+			c.error("Cannot revoke role", err, false)
+			return
+		}
+		c.loadAuth(ab)
+	})
+}
+
+func (c *Controller) addRole(ab *authBrowser) {
+	if c.readOnly() {
+		return
+	}
+	inp := c.view.NewRoleInput()
+	inp.SetDoneFunc(func(key tcell.Key) {
+		c.view.Pages.RemovePage("modal")
+		name := strings.TrimSpace(inp.GetText())
+		if key != tcell.KeyEnter || name == "" {
+			return
+		}
+		if err := c.model.AddRole(c.ctx, name); err != nil {
+			c.error("Cannot add role", err, false)
+			return
+		}
+		c.loadAuth(ab)
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 40, 5), true, true)
+}
+
+func (c *Controller) deleteRole(ab *authBrowser) {
+	r := ab.selectedRole()
+	if r == nil || c.readOnly() {
+		return
+	}
+	name := r.Name
+	c.confirm(fmt.Sprintf("Delete role %s?\nUsers holding it lose its permissions.", name), 60, 9, func() {
+		if err := c.model.DeleteRole(c.ctx, name); err != nil {
+			c.error("Cannot delete role", err, false)
+			return
+		}
+		c.loadAuth(ab)
+	})
+}
+
+// treePermission suggests a key range from the current tree position: the
+// selected directory as a prefix, the selected key alone, or else the
+// current directory as a prefix.
+func (c *Controller) treePermission() (key, match string) {
+	if nd := c.selectedNode(); nd != nil {
+		if nd.IsDir {
+			return normAbs(nd.Name) + "/", model.MatchPrefix
+		}
+		return nd.Name, model.MatchKey
+	}
+	return c.currentDir, model.MatchPrefix
+}
+
+// editPermission grants a new permission to role (idx < 0) or replaces
+// its permission idx.
+func (c *Controller) editPermission(ab *authBrowser, r *model.Role, idx int) {
+	if r == nil || c.readOnly() {
+		return
+	}
+	role := r.Name
+	var old *model.Permission
+	key, match := c.treePermission()
+	rangeEnd, typ := "", model.PermReadWrite
+	title := fmt.Sprintf("Grant permission to %s", tview.Escape(role))
+	if idx >= 0 {
+		if idx >= len(r.Perms) {
+			return
+		}
+		old = &r.Perms[idx]
+		key, match, typ = old.Key, old.Match(), old.Type
+		if match == model.MatchRange {
+			rangeEnd = old.RangeEnd
+		}
+		title = fmt.Sprintf("Edit permission of %s", tview.Escape(role))
+	}
+
+	form := c.view.NewPermissionForm(title, key, match, rangeEnd, typ, model.Matches, model.PermTypes)
+	keyField := form.GetFormItem(0).(*tview.InputField)
+	matchField := form.GetFormItem(1).(*tview.DropDown)
+	form.AddButton("Save", func() {
+		_, match := matchField.GetCurrentOption()
+		_, typ := form.GetFormItem(3).(*tview.DropDown).GetCurrentOption()
+		rangeEnd := form.GetFormItem(2).(*tview.InputField).GetText()
+		p, err := model.NewPermission(typ, keyField.GetText(), match, rangeEnd)
+		if err != nil {
+			c.error("Invalid permission", err, false)
+			return
+		}
+		c.view.Pages.RemovePage("modal")
+		if old != nil && *old == p {
+			return
+		}
+		// granting on the same range replaces the old type in place
+		err = c.model.GrantPermission(c.ctx, role, p)
+		if err == nil && old != nil && (old.Key != p.Key || old.RangeEnd != p.RangeEnd) {
+			err = c.model.RevokePermission(c.ctx, role, *old)
+		}
+		if err != nil {
+			c.error("Cannot change permission", err, false)
+			return
+		}
+		log.Debugf("Granted %s to role %s", p, role)
+		c.loadAuth(ab)
+	})
+	form.AddButton("From tree", func() {
+		key, match := c.treePermission()
+		keyField.SetText(key)
+		matchField.SetCurrentOption(max(slices.Index(model.Matches, match), 0))
+	})
+	c.addCancel(form)
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(form, 64, 13), true, true)
+}
+
+func (c *Controller) revokePermission(ab *authBrowser) {
+	r := ab.selectedRole()
+	i := ab.av.Items.GetCurrentItem()
+	if r == nil || i < 0 || i >= len(r.Perms) || c.readOnly() {
+		return
+	}
+	role, p := r.Name, r.Perms[i]
+	c.confirm(fmt.Sprintf("Revoke %s from role %s?", tview.Escape(p.String()), role), 60, 8, func() {
+		if err := c.model.RevokePermission(c.ctx, role, p); err != nil {
+			c.error("Cannot revoke permission", err, false)
+			return
+		}
+		c.loadAuth(ab)
+	})
+}
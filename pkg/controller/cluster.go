@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nexusriot/etcd-walker/pkg/model"
+	"github.com/nexusriot/etcd-walker/pkg/view"
+	"github.com/rivo/tview"
+)
+
+var clusterColumns = []string{"Name", "ID", "Role", "Client URL", "Version", "DB size (in use)", "Raft term", "Raft index"}
+
+// clusterStatus opens the member dashboard (Ctrl+D); m opens the
+// maintenance menu.
+func (c *Controller) clusterStatus() *tcell.EventKey {
+	cv := c.view.NewClusterView()
+	cv.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case ev.Key() == tcell.KeyEsc:
+			c.view.Pages.RemovePage("cluster")
+			return nil
+		case ev.Key() == tcell.KeyRune && ev.Rune() == 'r':
+			c.loadClusterStatus(cv)
+			return nil
+		case ev.Key() == tcell.KeyRune && ev.Rune() == 'm':
+			c.maintenanceMenu(cv)
+			return nil
+		}
+		return ev
+	})
+	c.view.Pages.AddPage("cluster", cv, true, true)
+	c.loadClusterStatus(cv)
+	return nil
+}
+
+// loadClusterStatus queries the members in the background; a slow or
+// unreachable member must not freeze the UI.
+func (c *Controller) loadClusterStatus(cv *view.ClusterView) {
+	m := c.model
+	cv.Details.SetText("Loading …")
+	go func() {
+		cs, err := m.ClusterStatus(c.ctx)
+		c.view.App.QueueUpdateDraw(func() {
+			if err != nil {
+				cv.Details.SetText(fmt.Sprintf("[red]Cannot read cluster status:[-] %s", tview.Escape(err.Error())))
+				return
+			}
+			fillClusterTable(cv, cs)
+		})
+	}()
+}
+
+func fillClusterTable(cv *view.ClusterView, cs *model.ClusterStatus) {
+	t := cv.Members
+	t.Clear()
+	for col, name := range clusterColumns {
+		t.SetCell(0, col, tview.NewTableCell(name).
+			SetTextColor(tcell.ColorYellow).
+			SetAttributes(tcell.AttrBold).
+			SetSelectable(false))
+	}
+
+	for i, mb := range cs.Members {
+		role := "follower"
+		switch {
+		case mb.IsLearner:
+			role = "learner"
+		case mb.ID == cs.Leader:
+			role = "leader"
+		}
+		clientURL := ""
+		if len(mb.ClientURLs) > 0 {
+			clientURL = mb.ClientURLs[0]
+		}
+		row := []string{mb.Name, mb.ID, role, clientURL, "-", "-", "-", "-"}
+		color := tcell.ColorWhite
+		switch {
+		case mb.Status != nil:
+			st := mb.Status
+			row[4] = st.Version
+			row[5] = fmt.Sprintf("%s (%s)", byteSize(st.DBSize), byteSize(st.DBSizeInUse))
+			row[6] = fmt.Sprintf("%d", st.RaftTerm)
+			row[7] = fmt.Sprintf("%d", st.RaftIndex)
+			if len(st.Errors) > 0 {
+				color = tcell.ColorRed
+			}
+		case mb.Err != nil:
+			color = tcell.ColorRed
+		}
+		if role == "leader" && color == tcell.ColorWhite {
+			color = tcell.ColorGreen
+		}
+		for col, text := range row {
+			t.SetCell(i+1, col, tview.NewTableCell(tview.Escape(text)).
+				SetTextColor(color).
+				SetExpansion(1))
+		}
+	}
+
+	show := func(row int) {
+		if row < 1 || row > len(cs.Members) {
+			return
+		}
+		cv.Details.SetText(memberDetails(cs, cs.Members[row-1]))
+	}
+	t.SetSelectionChangedFunc(func(row, _ int) { show(row) })
+	if len(cs.Members) > 0 {
+		t.Select(1, 0)
+		show(1)
+	} else {
+		cv.Details.SetText("No members reported.")
+	}
+}
+
+func memberDetails(cs *model.ClusterStatus, mb model.Member) string {
+	var sb strings.Builder
+	if cs.ClusterID != "" {
+		fmt.Fprintf(&sb, "[::b]Cluster ID:[::-] %s\n", cs.ClusterID)
+	}
+	fmt.Fprintf(&sb, "[::b]Member:[::-] %s (%s)\n", tview.Escape(mb.Name), mb.ID)
+	fmt.Fprintf(&sb, "[::b]Peer URLs:[::-] %s\n", tview.Escape(strings.Join(mb.PeerURLs, ", ")))
+	fmt.Fprintf(&sb, "[::b]Client URLs:[::-] %s\n", tview.Escape(strings.Join(mb.ClientURLs, ", ")))
+	switch {
+	case mb.Status != nil:
+		st := mb.Status
+		fmt.Fprintf(&sb, "[::b]Raft applied index:[::-] %d\n", st.RaftAppliedIndex)
+		fmt.Fprintf(&sb, "[::b]DB size:[::-] %d bytes, %d in use\n", st.DBSize, st.DBSizeInUse)
+		for _, e := range st.Errors {
+			fmt.Fprintf(&sb, "[red]Alarm/error:[-] %s\n", tview.Escape(e))
+		}
+	case mb.Err != nil:
+		fmt.Fprintf(&sb, "[red]Status unavailable:[-] %s\n", tview.Escape(mb.Err.Error()))
+	default:
+		sb.WriteString("[dim]Per-member status is only available on v3.[-]\n")
+	}
+	return sb.String()
+}
+
+// byteSize formats n using binary units.
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
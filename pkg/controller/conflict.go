@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nexusriot/etcd-walker/pkg/model"
+	"github.com/nexusriot/etcd-walker/pkg/util/diff"
+	log "github.com/sirupsen/logrus"
+)
+
+// editSession remembers what an editor was opened with so that saving can
+// detect changes made by someone else in the meantime.
+type editSession struct {
+	node *model.Node
+	base string // value shown when the editor was (re)loaded
+	rev  int64  // node.Revision() at that time; 0 if unknown
+}
+
+func newEditSession(nd *model.Node) *editSession {
+	return &editSession{node: nd, base: nd.Value, rev: nd.Revision()}
+}
+
+// save writes value with a compare-and-swap on the session revision. On a
+// conflict it asks the user to overwrite, reload or inspect a diff and
+// returns nil; saved runs after any successful write and reload receives
+// the server's current value. Other errors are returned to the caller.
+func (c *Controller) save(s *editSession, value string, saved func(), reload func(string)) error {
+	key := s.node.Name
+	var err error
+	if s.rev > 0 {
+		err = c.model.SetIfUnchanged(c.ctx, key, value, s.rev)
+	} else {
+		// injected nodes carry no revision; nothing to compare against
+		err = c.model.Set(c.ctx, key, value)
+	}
+	if errors.Is(err, model.ErrConflict) {
+		log.Debugf("save conflict on %s (expected rev %d)", key, s.rev)
+		c.conflict(s, value, saved, reload)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	saved()
+	return nil
+}
+
+func (c *Controller) conflict(s *editSession, value string, saved func(), reload func(string)) {
+	pages := c.view.ActivePages()
+	q := c.view.NewConflictQ(s.node.Name)
+	q.SetDoneFunc(func(_ int, buttonLabel string) {
+		switch buttonLabel {
+		case "Overwrite":
+			pages.RemovePage("modal-conflict")
+			c.overwrite(s, value, saved, reload)
+		case "Reload":
+			pages.RemovePage("modal-conflict")
+			cur, err := c.model.Get(c.ctx, s.node.Name)
+			if err != nil {
+				// deleted meanwhile: start over from an empty, new key
+				cur = &model.Node{Name: s.node.Name}
+			}
+			s.base, s.rev = cur.Value, cur.Revision()
+			reload(cur.Value)
+		case "Diff":
+			c.conflictDiff(s, value)
+		default:
+			pages.RemovePage("modal-conflict")
+		}
+	})
+	pages.AddPage("modal-conflict", c.view.ModalEdit(q, 60, 9), true, true)
+}
+
+// overwrite re-reads the key and writes value over the revision it finds.
+// That keeps the key's lease or TTL like any other save, and a writer
+// racing in between brings the conflict dialog back.
+func (c *Controller) overwrite(s *editSession, value string, saved func(), reload func(string)) {
+	var rev int64 // 0: deleted meanwhile, value recreates it
+	cur, err := c.model.Get(c.ctx, s.node.Name)
+	switch {
+	case err == nil:
+		rev = cur.Revision()
+	case !errors.Is(err, model.ErrNotFound):
+		c.error("Failed to save value", err, false)
+		return
+	}
+	err = c.model.SetIfUnchanged(c.ctx, s.node.Name, value, rev)
+	if errors.Is(err, model.ErrConflict) {
+		log.Debugf("overwrite conflict on %s (expected rev %d)", s.node.Name, rev)
+		c.conflict(s, value, saved, reload)
+		return
+	}
+	if err != nil {
+		c.error("Failed to save value", err, false)
+		return
+	}
+	saved()
+}
+
+// conflictDiff shows two diffs from the value the editor was opened with:
+// what the other writer changed (base → server) and what the user changed
+// (base → editor). It does not merge them.
+func (c *Controller) conflictDiff(s *editSession, value string) {
+	pages := c.view.ActivePages()
+	tv := c.view.NewDiffView(fmt.Sprintf("Conflict: %s", s.node.Name))
+
+	theirs, theirsLabel := "", "deleted on server"
+	if cur, err := c.model.Get(c.ctx, s.node.Name); err == nil {
+		theirs = cur.Value
+		theirsLabel = fmt.Sprintf("server, rev %d", cur.Revision())
+	}
+
+	fmt.Fprintf(tv, "[::b]Their changes (base → %s)[::-]\n", theirsLabel)
+	writeDiff(tv, diff.Lines(s.base, theirs))
+	fmt.Fprintf(tv, "\n[::b]Your changes (base → editor)[::-]\n")
+	writeDiff(tv, diff.Lines(s.base, value))
+	tv.ScrollToBeginning()
+
+	tv.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEsc {
+			pages.RemovePage("modal-diff")
+			return nil
+		}
+		return ev
+	})
+	pages.AddPage("modal-diff", tv, true, true)
+}
@@ -1,17 +1,23 @@
 package controller
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/nexusriot/etcd-walker/pkg/export"
 	"github.com/nexusriot/etcd-walker/pkg/model"
 	"github.com/nexusriot/etcd-walker/pkg/util/clip"
 	"github.com/nexusriot/etcd-walker/pkg/view"
@@ -20,14 +26,31 @@ import (
 )
 
 type Controller struct {
+	// ctx ends with the UI; requests still in flight are abandoned
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	debug        bool
 	view         *view.View
 	model        *model.Model
+	opts         model.Options
+	profile      Profile
+	profiles     []Profile
 	currentDir   string
 	currentNodes map[string]*Node // mapKey => Node (mapKey is "<basename>|dir" or "<basename>|file")
 	position     map[string]int
 	injected     map[string]map[string]*model.Node
 
+	// live refresh of currentDir (see subscribe)
+	watchDir  string
+	watchStop func()
+
+	// cancels the details being loaded for the selection (see fillDetails)
+	detailsCancel context.CancelFunc
+
+	// narrows the listing as the user types (see openFilter)
+	filter listFilter
+
 	startupErr error
 }
 
@@ -37,41 +60,92 @@ type Node struct {
 
 func splitFunc(r rune) bool { return r == '/' }
 
-func NewController(opts model.Options, debug bool) *Controller {
-	m, err := model.NewModel(opts)
-
-	v := view.NewView()
-
-	headerProto := opts.Protocol
-	auth := "?"
-	if err == nil && m != nil {
-		headerProto = m.ProtocolVersion()
-		auth = m.AuthLabel()
-	}
+// Profile is a named connection; see switchProfile.
+type Profile struct {
+	Name    string
+	Options model.Options
+	Color   string // header color: tcell name or #rrggbb, empty for default
+}
 
-	tlsTag := ""
-	if opts.TLSEnabled {
-		tlsTag = " [TLS]"
-	}
+func NewController(current Profile, profiles []Profile, debug bool) *Controller {
+	ctx, cancel := context.WithCancel(context.Background())
+	m, err := model.NewModel(ctx, current.Options)
 
-	v.Frame.AddText(
-		fmt.Sprintf("Etcd-walker v.0.5.1 (on %s:%s%s)  –  protocol: %s  |  Auth: %s",
-			opts.Host, opts.Port, tlsTag, headerProto, auth),
-		true, tview.AlignCenter, tcell.ColorGreen,
-	)
+	v := view.NewView()
 
 	controller := &Controller{
+		ctx:        ctx,
+		cancel:     cancel,
 		debug:      debug,
 		view:       v,
 		model:      m,
+		opts:       current.Options,
+		profile:    current,
+		profiles:   profiles,
 		currentDir: "/",
 		position:   make(map[string]int),
 		injected:   make(map[string]map[string]*model.Node),
 		startupErr: err,
 	}
+	if m != nil {
+		controller.currentDir = m.StartDir()
+	}
+	controller.updateHeader()
 	return controller
 }
 
+// updateHeader redraws the status line; call it whenever connection state
+// shown there (endpoint, protocol, auth, pinned revision) changes.
+func (c *Controller) updateHeader() {
+	headerProto := c.opts.Protocol
+	auth := "?"
+	endpoint := strings.Join(c.opts.Endpoints, ",")
+	if endpoint == "" {
+		endpoint = net.JoinHostPort(strings.Trim(c.opts.Host, "[]"), c.opts.Port)
+	}
+	if c.opts.Snapshot != "" {
+		endpoint = c.opts.Snapshot
+	}
+	if c.startupErr == nil && c.model != nil {
+		headerProto = c.model.ProtocolVersion()
+		auth = c.model.AuthLabel()
+		endpoint = c.model.Endpoint()
+		if n := len(c.model.Endpoints()); n > 1 {
+			endpoint += fmt.Sprintf(", %d endpoints", n)
+		}
+	}
+	endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "http://"), "https://")
+
+	tlsTag := ""
+	if c.opts.UseTLS() {
+		tlsTag = " " + tview.Escape("[TLS]")
+	}
+	roTag := ""
+	if c.opts.ReadOnly || c.opts.Snapshot != "" {
+		roTag = "[red::b]" + tview.Escape("[RO]") + "[-::-] "
+	}
+
+	profileTag := ""
+	if c.profile.Name != "" {
+		profileTag = " " + tview.Escape("["+c.profile.Name+"]")
+	}
+
+	header := fmt.Sprintf("%sEtcd-walker v.0.5.1%s (on %s%s)  –  protocol: %s  |  Auth: %s",
+		roTag, profileTag, endpoint, tlsTag, headerProto, auth)
+	if c.model != nil {
+		if rev := c.model.PinnedRevision(); rev > 0 {
+			header += fmt.Sprintf("  |  [yellow::b]@rev %d (read-only)[-::-]", rev)
+		}
+	}
+	color := tcell.ColorGreen
+	if c.profile.Color != "" {
+		if pc := tcell.GetColor(c.profile.Color); pc != tcell.ColorDefault {
+			color = pc
+		}
+	}
+	c.view.SetHeader(header, color)
+}
+
 // makeMapKey ensures uniqueness when file and dir share the same basename.
 func makeMapKey(base string, isDir bool) string {
 	if isDir {
@@ -178,10 +252,10 @@ func (c *Controller) removeInjected(nd *model.Node) {
 }
 
 // re-inject after rename (old -> new)
-func (c *Controller) reinjectRename(oldName, newName string, isDir bool, clusterID, value string) {
-	old := &model.Node{Name: normAbs(oldName), IsDir: isDir, ClusterId: clusterID, Value: value}
+func (c *Controller) reinjectRename(oldName, newName string, n *model.Node) {
+	old := &model.Node{Name: normAbs(oldName), IsDir: n.IsDir, ClusterId: n.ClusterId, Value: n.Value, Lazy: n.Lazy}
 	c.removeInjected(old)
-	newN := &model.Node{Name: normAbs(newName), IsDir: isDir, ClusterId: clusterID, Value: value}
+	newN := &model.Node{Name: normAbs(newName), IsDir: n.IsDir, ClusterId: n.ClusterId, Value: n.Value, Lazy: n.Lazy}
 	c.injectNode(newN)
 }
 
@@ -190,7 +264,7 @@ func (c *Controller) makeNodeMap() error {
 	m := make(map[string]*Node)
 
 	// Model-provided listing
-	list, err := c.model.Ls(c.currentDir)
+	list, err := c.model.Ls(c.ctx, c.currentDir)
 	if err != nil {
 		return err
 	}
@@ -234,11 +308,43 @@ func (c *Controller) colorize(base string, isDir bool, label string) string {
 
 func (c *Controller) updateList() []string {
 	log.Debugf("updating list")
-	c.view.List.Clear()
-	c.view.List.SetTitle("[ [::b]" + c.currentDir + "[::-] ]")
-	if err := c.makeNodeMap(); err != nil {
+	err := c.makeNodeMap()
+	if errors.Is(err, model.ErrCompacted) && c.model.PinnedRevision() > 0 {
+		// The pinned revision was compacted away while we were browsing it;
+		// fall back to the latest data rather than failing.
+		rev := c.model.PinnedRevision()
+		_ = c.model.PinRevision(c.ctx, 0)
+		c.updateHeader()
+		defer c.error("Revision compacted", fmt.Errorf("revision %d is no longer available; showing latest data", rev), false)
+		err = c.makeNodeMap()
+	}
+	if errors.Is(err, model.ErrPermissionDenied) {
+		c.currentNodes = map[string]*Node{}
+		c.updateHeader()
+		ordered := c.renderList()
+		c.showDenied(err)
+		return ordered
+	}
+	if err != nil {
 		c.error("failed to load nodes", err, true)
 	}
+	c.updateHeader()
+	return c.renderList()
+}
+
+// renderList rebuilds the list widget from currentNodes and returns the
+// display names in list order (without the leading "[..]").
+func (c *Controller) renderList() []string {
+	if c.filter.text != "" && c.filter.dir != c.currentDir {
+		// the filter belonged to the directory we just left
+		c.filter.set("", c.filter.mode)
+		c.view.Filter.SetText("")
+		c.view.HideFilter()
+	}
+	filtering := c.filter.active(c.currentDir)
+
+	c.view.List.Clear()
+	c.view.List.SetTitle("[ [::b]" + c.currentDir + "[::-] ]")
 
 	// [..] always on top
 	c.view.List.AddItem("[..]", "..", 0, func() {
@@ -248,7 +354,15 @@ func (c *Controller) updateList() []string {
 	// Collect and split into dirs and files using mapKey suffix.
 	dirKeys := make([]string, 0, len(c.currentNodes))  // mapKey
 	fileKeys := make([]string, 0, len(c.currentNodes)) // mapKey
-	for mk := range c.currentNodes {
+	hits := map[string][]bool{}                        // mapKey => matched runes of the name
+	for mk, v := range c.currentNodes {
+		if filtering {
+			hit, ok := c.filter.match(baseOf(v.node.Name))
+			if !ok {
+				continue
+			}
+			hits[mk] = hit
+		}
 		if strings.HasSuffix(mk, "|dir") {
 			dirKeys = append(dirKeys, mk)
 		} else {
@@ -257,14 +371,21 @@ func (c *Controller) updateList() []string {
 	}
 	sort.Strings(dirKeys)
 	sort.Strings(fileKeys)
+	if filtering {
+		c.view.List.SetTitle(fmt.Sprintf("[ [::b]%s[::-] ] %d of %d", c.currentDir, len(dirKeys)+len(fileKeys), len(c.currentNodes)))
+	}
 
 	// Directories
 	for _, mk := range dirKeys {
 		n := c.currentNodes[mk].node
 		fields := strings.FieldsFunc(n.Name, splitFunc)
 		base := fields[len(fields)-1]
-		rawLabel := "📁 " + displayName(base, true)
-		label := c.colorize(base, true, rawLabel)
+		name := displayName(base, true)
+		if hit, ok := hits[mk]; ok {
+			name = displayName(highlight(base, hit), true)
+		}
+		rawLabel := "📁 " + name
+		label := c.colorize(base, true, rawLabel) + accessMark(n.Access)
 		// Use mapKey as secondary text (stable key for actions)
 		c.view.List.AddItem(label, mk, 0, func() {
 			i := c.view.List.GetCurrentItem()
@@ -285,8 +406,12 @@ func (c *Controller) updateList() []string {
 		n := c.currentNodes[mk].node
 		fields := strings.FieldsFunc(n.Name, splitFunc)
 		base := fields[len(fields)-1]
-		rawLabel := "   " + displayName(base, false)
-		label := c.colorize(base, false, rawLabel)
+		name := displayName(base, false)
+		if hit, ok := hits[mk]; ok {
+			name = highlight(base, hit)
+		}
+		rawLabel := "   " + name
+		label := c.colorize(base, false, rawLabel) + accessMark(n.Access)
 		c.view.List.AddItem(label, mk, 0, func() {
 			// no-op; details pane updates via SetChangedFunc
 		})
@@ -312,87 +437,234 @@ func (c *Controller) updateList() []string {
 	return ordered
 }
 
+// loadValue makes sure val carries its key's value; v3 listings leave
+// values out and they are only fetched once a key is selected or used.
+func (c *Controller) loadValue(val *Node) error {
+	n, err := c.model.Load(c.ctx, val.node)
+	if err != nil {
+		return err
+	}
+	val.node = n
+	return nil
+}
+
+// spinnerFrames animate the details pane while it loads.
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+// cancelDetails abandons the details being loaded, if any.
+func (c *Controller) cancelDetails() {
+	if c.detailsCancel != nil {
+		c.detailsCancel()
+		c.detailsCancel = nil
+	}
+}
+
+// fillDetails shows what is known about the selected node at once and
+// loads the rest (a key's value, a directory's children) in the
+// background, so moving the cursor never waits for etcd. Moving on
+// cancels the load.
 func (c *Controller) fillDetails(mapKey string) {
+	c.cancelDetails()
 	c.view.Details.Clear()
 
 	val, ok := c.currentNodes[mapKey]
 	if !ok {
 		return
 	}
-
 	n := val.node
-	base := baseOf(n.Name)
-	parent := parentOf(n.Name)
-
-	fmt.Fprintf(c.view.Details, "[::b]Path info[::-]\n")
-	fmt.Fprintf(c.view.Details, "  [green]Type:[-] %s\n", map[bool]string{true: "Directory", false: "Key"}[n.IsDir])
-	fmt.Fprintf(c.view.Details, "  [green]Basename:[-] %s\n", base)
-	fmt.Fprintf(c.view.Details, "  [green]Parent:[-] %s\n", parent)
-	fmt.Fprintf(c.view.Details, "  [green]Full path:[-] %s\n", n.Name)
-	fmt.Fprintf(c.view.Details, "  [green]Depth:[-] %d\n", depthOf(n.Name))
-
-	fmt.Fprintf(c.view.Details, "\n[::b]Cluster info[::-]\n")
-	fmt.Fprintf(c.view.Details, "  [green]Protocol:[-] %s\n", c.model.ProtocolVersion())
-	fmt.Fprintf(c.view.Details, "  [green]Cluster ID:[-] %s\n", n.ClusterId)
-
-	if !n.IsDir {
-		bytes, lines, printable := valueStats(n.Value)
-
-		fmt.Fprintf(c.view.Details, "\n[::b]Value info[::-]\n")
-		fmt.Fprintf(c.view.Details, "  [green]Size:[-] %d bytes\n", bytes)
-		fmt.Fprintf(c.view.Details, "  [green]Lines:[-] %d\n", lines)
-		fmt.Fprintf(c.view.Details, "  [green]SHA-256:[-] %s\n", shortHash(n.Value))
-
-		const previewLimit = 512
-		if printable {
-			fmt.Fprintf(c.view.Details, "\n[::b]Preview (%d chars)[::-]\n", previewLimit)
-			if len(n.Value) > previewLimit {
-				fmt.Fprintf(c.view.Details, "%s…\n", n.Value[:previewLimit])
-			} else {
-				fmt.Fprintf(c.view.Details, "%s\n", n.Value)
+	if !n.IsDir && !n.Lazy {
+		c.view.Details.SetText(c.detailsHead(n) + valueInfo(n))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.detailsCancel = cancel
+	head := c.detailsHead(n)
+	c.view.Details.SetText(head + "\n" + string(spinnerFrames[0]) + " loading …\n")
+
+	go func() {
+		t := time.NewTicker(100 * time.Millisecond)
+		defer t.Stop()
+		for i := 1; ; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
 			}
-		} else {
-			fmt.Fprintf(c.view.Details, "\n[yellow]Binary / non-UTF8 value (preview suppressed)[-]\n")
+			frame := string(spinnerFrames[i%len(spinnerFrames)])
+			c.view.App.QueueUpdateDraw(func() {
+				if ctx.Err() == nil {
+					c.view.Details.SetText(head + "\n" + frame + " loading …\n")
+				}
+			})
 		}
-	} else {
-		dirPath := normAbs(n.Name)
-		if dirPath != "/" && !strings.HasSuffix(dirPath, "/") {
-			dirPath = dirPath + "/"
+	}()
+
+	m := c.model
+	go func() {
+		var (
+			tail   string
+			loaded *model.Node
+		)
+		if n.IsDir {
+			list, err := m.Ls(ctx, withTrailing(n.Name))
+			tail = dirInfo(list, err)
+		} else {
+			var err error
+			if loaded, err = m.Load(ctx, n); err != nil {
+				tail = fmt.Sprintf("\n[::b]Value info[::-]\n  [red]Cannot load value:[-] %s\n", tview.Escape(err.Error()))
+			}
 		}
+		c.view.App.QueueUpdateDraw(func() {
+			// the cursor moved on while this was loading
+			if ctx.Err() != nil {
+				return
+			}
+			cancel()
+			c.detailsCancel = nil
+			if loaded != nil {
+				val.node = loaded
+				head, tail = c.detailsHead(loaded), valueInfo(loaded)
+			}
+			c.view.Details.SetText(head + tail)
+		})
+	}()
+}
 
-		list, err := c.model.Ls(dirPath)
-		if err != nil {
-			fmt.Fprintf(c.view.Details, "\n[::b]Directory info[::-]\n")
-			fmt.Fprintf(c.view.Details, "  [red]Failed to list children:[-] %s\n", err.Error())
-			return
+// withTrailing is dir as a listing prefix, with its trailing slash.
+func withTrailing(dir string) string {
+	dir = normAbs(dir)
+	if dir != "/" {
+		dir += "/"
+	}
+	return dir
+}
+
+// detailsHead renders the parts of the details that need no request.
+func (c *Controller) detailsHead(n *model.Node) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[::b]Path info[::-]\n")
+	fmt.Fprintf(&sb, "  [green]Type:[-] %s\n", map[bool]string{true: "Directory", false: "Key"}[n.IsDir])
+	fmt.Fprintf(&sb, "  [green]Basename:[-] %s\n", baseOf(n.Name))
+	fmt.Fprintf(&sb, "  [green]Parent:[-] %s\n", parentOf(n.Name))
+	fmt.Fprintf(&sb, "  [green]Full path:[-] %s\n", n.Name)
+	fmt.Fprintf(&sb, "  [green]Depth:[-] %d\n", depthOf(n.Name))
+	if c.model.Restricted() {
+		fmt.Fprintf(&sb, "  [green]Access:[-] %s\n", n.Access)
+	}
+
+	fmt.Fprintf(&sb, "\n[::b]Cluster info[::-]\n")
+	fmt.Fprintf(&sb, "  [green]Protocol:[-] %s\n", c.model.ProtocolVersion())
+	fmt.Fprintf(&sb, "  [green]Cluster ID:[-] %s\n", n.ClusterId)
+	if rev := c.model.PinnedRevision(); rev > 0 {
+		fmt.Fprintf(&sb, "  [green]Revision:[-] %d [yellow](pinned, read-only)[-]\n", rev)
+	}
+
+	writeRevisionInfo(&sb, n)
+	return sb.String()
+}
+
+func valueInfo(n *model.Node) string {
+	var sb strings.Builder
+	bytes, lines, printable := valueStats(n.Value)
+
+	fmt.Fprintf(&sb, "\n[::b]Value info[::-]\n")
+	fmt.Fprintf(&sb, "  [green]Size:[-] %d bytes\n", bytes)
+	fmt.Fprintf(&sb, "  [green]Lines:[-] %d\n", lines)
+	fmt.Fprintf(&sb, "  [green]SHA-256:[-] %s\n", shortHash(n.Value))
+
+	const previewLimit = 512
+	if printable {
+		fmt.Fprintf(&sb, "\n[::b]Preview (%d chars)[::-]\n", previewLimit)
+		if len(n.Value) > previewLimit {
+			fmt.Fprintf(&sb, "%s…\n", n.Value[:previewLimit])
+		} else {
+			fmt.Fprintf(&sb, "%s\n", n.Value)
 		}
+	} else {
+		fmt.Fprintf(&sb, "\n[yellow]Binary / non-UTF8 value (preview suppressed)[-]\n")
+	}
+	return sb.String()
+}
 
-		subdirs := 0
-		keys := 0
-		seen := make(map[string]struct{}, len(list))
-		for _, ch := range list {
-			if ch == nil {
-				continue
-			}
-			b := baseOf(ch.Name)
-			mk := makeMapKey(b, ch.IsDir)
-			if _, ok := seen[mk]; ok {
-				continue
-			}
-			seen[mk] = struct{}{}
-			if ch.IsDir {
-				subdirs++
-			} else {
-				keys++
-			}
+func dirInfo(list []*model.Node, err error) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\n[::b]Directory info[::-]\n")
+	if err != nil {
+		fmt.Fprintf(&sb, "  [red]Failed to list children:[-] %s\n", err.Error())
+		return sb.String()
+	}
+
+	subdirs := 0
+	keys := 0
+	seen := make(map[string]struct{}, len(list))
+	for _, ch := range list {
+		if ch == nil {
+			continue
+		}
+		b := baseOf(ch.Name)
+		mk := makeMapKey(b, ch.IsDir)
+		if _, ok := seen[mk]; ok {
+			continue
 		}
+		seen[mk] = struct{}{}
+		if ch.IsDir {
+			subdirs++
+		} else {
+			keys++
+		}
+	}
 
-		fmt.Fprintf(c.view.Details, "\n[::b]Directory info[::-]\n")
-		fmt.Fprintf(c.view.Details, "  [green]Children:[-] %d\n", subdirs+keys)
-		fmt.Fprintf(c.view.Details, "  [green]Subdirs:[-] %d\n", subdirs)
-		fmt.Fprintf(c.view.Details, "  [green]Keys:[-] %d\n", keys)
+	fmt.Fprintf(&sb, "  [green]Children:[-] %d\n", subdirs+keys)
+	fmt.Fprintf(&sb, "  [green]Subdirs:[-] %d\n", subdirs)
+	fmt.Fprintf(&sb, "  [green]Keys:[-] %d\n", keys)
+	return sb.String()
+}
+
+// selectedNode returns the node under the cursor, or nil on "[..]".
+func (c *Controller) selectedNode() *model.Node {
+	if c.view.List.GetItemCount() == 0 {
+		return nil
+	}
+	_, mapKey := c.view.List.GetItemText(c.view.List.GetCurrentItem())
+	if val, ok := c.currentNodes[strings.TrimSpace(mapKey)]; ok {
+		return val.node
 	}
+	return nil
+}
 
+// writeRevisionInfo renders the MVCC (v3) or index/TTL (v2) metadata of n.
+func writeRevisionInfo(w io.Writer, n *model.Node) {
+	switch {
+	case n.ModRevision > 0:
+		fmt.Fprintf(w, "\n[::b]Revision info[::-]\n")
+		fmt.Fprintf(w, "  [green]Create revision:[-] %d\n", n.CreateRevision)
+		fmt.Fprintf(w, "  [green]Mod revision:[-] %d\n", n.ModRevision)
+		fmt.Fprintf(w, "  [green]Version:[-] %d\n", n.Version)
+		if n.Lease != 0 {
+			fmt.Fprintf(w, "  [green]Lease:[-] %x\n", n.Lease)
+		} else {
+			fmt.Fprintf(w, "  [green]Lease:[-] none\n")
+		}
+	case n.ModifiedIndex > 0:
+		fmt.Fprintf(w, "\n[::b]Revision info[::-]\n")
+		fmt.Fprintf(w, "  [green]Created index:[-] %d\n", n.CreatedIndex)
+		fmt.Fprintf(w, "  [green]Modified index:[-] %d\n", n.ModifiedIndex)
+		if n.Expiration == nil {
+			fmt.Fprintf(w, "  [green]TTL:[-] none\n")
+			return
+		}
+		left := time.Until(*n.Expiration).Round(time.Second)
+		color := "white"
+		if left < time.Minute {
+			color = "red"
+		} else if left < 10*time.Minute {
+			color = "yellow"
+		}
+		fmt.Fprintf(w, "  [green]TTL:[-] %ds\n", n.TTL)
+		fmt.Fprintf(w, "  [green]Expires:[-] [%s]%s (in %s)[-]\n",
+			color, n.Expiration.Local().Format(time.DateTime), left)
+	}
 }
 
 func (c *Controller) getPosition(element string, slice []string) int {
@@ -412,6 +684,20 @@ func (c *Controller) info(header, details string) {
 	c.view.Pages.AddPage("modal-info", c.view.ModalEdit(m, 60, 7), true, true)
 }
 
+// readOnly reports whether writes are disabled and tells the user so;
+// the model refuses them anyway, this just avoids opening a dead dialog.
+func (c *Controller) readOnly() bool {
+	if !c.model.ReadOnly() {
+		return false
+	}
+	reason := "started with --read-only"
+	if c.opts.Snapshot != "" {
+		reason = "browsing a snapshot file"
+	}
+	c.error("Read-only mode", fmt.Errorf("%w: %s, changes are disabled", model.ErrReadOnly, reason), false)
+	return true
+}
+
 func (c *Controller) copied(header, details string) {
 	m := c.view.NewCopiedMessageQ(header, details)
 	m.SetDoneFunc(func(int, string) {
@@ -485,6 +771,10 @@ func (c *Controller) copyValue() *tcell.EventKey {
 		return nil
 	}
 
+	if err := c.loadValue(val); err != nil {
+		c.error("Cannot read value", err, false)
+		return nil
+	}
 	if err := clip.Copy(val.node.Value); err != nil {
 		c.error("Clipboard error", err, false)
 		return nil
@@ -519,10 +809,28 @@ func (c *Controller) setInput() {
 			return c.copyValue()
 		case tcell.KeyCtrlS:
 			return c.search()
+		case tcell.KeyCtrlF:
+			return c.find()
 		case tcell.KeyCtrlJ:
 			return c.jump()
 		case tcell.KeyCtrlW:
 			return c.export()
+		case tcell.KeyCtrlO:
+			return c.importJSON()
+		case tcell.KeyCtrlT:
+			return c.revision()
+		case tcell.KeyCtrlL:
+			return c.history()
+		case tcell.KeyCtrlG:
+			return c.switchProfile()
+		case tcell.KeyCtrlD:
+			return c.clusterStatus()
+		case tcell.KeyCtrlA:
+			return c.leases()
+		case tcell.KeyCtrlU:
+			return c.manageAuth()
+		case tcell.KeyCtrlB:
+			return c.saveSnapshot()
 		case tcell.KeyCtrlH:
 			help := c.view.NewHotkeysModal()
 
@@ -531,17 +839,25 @@ func (c *Controller) setInput() {
 				return nil
 			})
 
-			c.view.Pages.AddPage("modal-help", c.view.ModalEdit(help, 70, 25), true, true)
+			c.view.Pages.AddPage("modal-help", c.view.ModalEdit(help, 70, 38), true, true)
 			return nil
 
 		case tcell.KeyBackspace2:
 			c.Up()
 			return nil
 
+		case tcell.KeyEsc:
+			if c.filter.text != "" {
+				c.clearFilter()
+				return nil
+			}
+
 		case tcell.KeyRune:
 			switch event.Rune() {
 			case '/':
 				return c.search()
+			case 'f':
+				return c.openFilter()
 			}
 		}
 		return event
@@ -575,10 +891,114 @@ func (c *Controller) Up() {
 	c.Cd(c.currentDir)
 }
 
-func (c *Controller) Cd(path string) { c.updateList() }
+func (c *Controller) Cd(path string) {
+	c.updateList()
+	c.subscribe()
+}
+
+// subscribe (re)starts the etcd watch on currentDir so that changes made by
+// other clients show up without a manual refresh. It is a no-op while the
+// existing watch already covers currentDir.
+func (c *Controller) subscribe() {
+	dir := c.currentDir
+	if c.watchStop != nil && c.watchDir == dir {
+		return
+	}
+	c.unsubscribe()
+
+	events, stop := c.model.Watch(c.ctx, dir)
+	c.watchDir = dir
+	c.watchStop = stop
+	log.Debugf("watching %s", dir)
+	go c.watchLoop(dir, events)
+}
+
+func (c *Controller) unsubscribe() {
+	if c.watchStop != nil {
+		c.watchStop()
+		c.watchStop = nil
+		c.watchDir = ""
+	}
+}
+
+// watchLoop coalesces bursts of events (a recursive delete produces one per
+// key) and schedules a single refresh on the UI goroutine for each burst.
+func (c *Controller) watchLoop(dir string, events <-chan []model.WatchEvent) {
+	const settle = 200 * time.Millisecond
+	for batch := range events {
+		if batch[0].Resync {
+			log.Debugf("watch %s: re-established, re-reading", dir)
+		} else {
+			log.Debugf("watch %s: %d event(s), first %s", dir, len(batch), batch[0].Key)
+		}
+		timer := time.NewTimer(settle)
+	drain:
+		for {
+			select {
+			case _, ok := <-events:
+				if !ok {
+					timer.Stop()
+					return
+				}
+			case <-timer.C:
+				break drain
+			}
+		}
+		c.view.App.QueueUpdateDraw(func() {
+			// A pinned revision never changes, so there is nothing to redraw.
+			if c.currentDir == dir && c.model.PinnedRevision() == 0 {
+				c.refresh()
+			}
+		})
+	}
+}
+
+// refresh re-reads currentDir and keeps the cursor on the same entry
+// (matched by mapKey), falling back to the same row if it is gone.
+func (c *Controller) refresh() {
+	idx := c.view.List.GetCurrentItem()
+	mk := ""
+	if c.view.List.GetItemCount() > 0 {
+		_, mk = c.view.List.GetItemText(idx)
+		mk = strings.TrimSpace(mk)
+	}
+
+	// Errors here are transient (leader change, timeouts); keep the stale
+	// listing instead of interrupting the user with a fatal modal.
+	if err := c.makeNodeMap(); err != nil {
+		log.Debugf("refresh of %s failed: %v", c.currentDir, err)
+		return
+	}
+	c.updateHeader()
+	c.renderList()
+
+	if pos := c.findMapKey(mk); pos >= 0 {
+		idx = pos
+	} else if idx >= c.view.List.GetItemCount() {
+		idx = c.view.List.GetItemCount() - 1
+	}
+	c.view.List.SetCurrentItem(idx)
+	_, cur := c.view.List.GetItemText(c.view.List.GetCurrentItem())
+	c.fillDetails(strings.TrimSpace(cur))
+}
+
+// findMapKey returns the list index of the entry with the given mapKey or -1.
+func (c *Controller) findMapKey(mapKey string) int {
+	if mapKey == "" {
+		return -1
+	}
+	for i := 0; i < c.view.List.GetItemCount(); i++ {
+		if _, mk := c.view.List.GetItemText(i); strings.TrimSpace(mk) == mapKey {
+			return i
+		}
+	}
+	return -1
+}
 
 func (c *Controller) Stop() {
 	log.Debugf("exit...")
+	c.unsubscribe()
+	c.cancel()
 	c.view.App.Stop()
 }
 
@@ -611,7 +1031,8 @@ func (c *Controller) Run() error {
 		curMK := strings.TrimSpace(secondary) // mapKey
 		c.fillDetails(curMK)
 	})
-	c.updateList()
+	c.setupFilter()
+	c.Cd(c.currentDir)
 	c.setInput()
 	return c.view.App.Run()
 }
@@ -622,9 +1043,15 @@ func (c *Controller) search() *tcell.EventKey {
 	// Recompute visual ordering to match the list
 	dirNames := []string{}
 	fileNames := []string{}
+	filtering := c.filter.active(c.currentDir)
 	for mk, v := range c.currentNodes {
 		fs := strings.FieldsFunc(v.node.Name, splitFunc)
 		base := fs[len(fs)-1]
+		if filtering {
+			if _, ok := c.filter.match(base); !ok {
+				continue // hidden by the filter bar
+			}
+		}
 		if strings.HasSuffix(mk, "|dir") {
 			dirNames = append(dirNames, displayName(base, true))
 		} else {
@@ -665,10 +1092,12 @@ func (c *Controller) search() *tcell.EventKey {
 }
 
 func (c *Controller) delete() *tcell.EventKey {
+	if c.readOnly() {
+		return nil
+	}
 	if c.view.List.GetItemCount() == 0 {
 		return nil
 	}
-	var err error
 	i := c.view.List.GetCurrentItem()
 	_, mapKey := c.view.List.GetItemText(i) // secondary text is mapKey
 	mapKey = strings.TrimSpace(mapKey)
@@ -678,56 +1107,99 @@ func (c *Controller) delete() *tcell.EventKey {
 	}
 
 	if val, ok := c.currentNodes[mapKey]; ok {
+		if c.writeDenied(val.node) {
+			return nil
+		}
 		base := displayName(strings.FieldsFunc(val.node.Name, splitFunc)[len(strings.FieldsFunc(val.node.Name, splitFunc))-1], val.node.IsDir)
 		elem := base
 		if val.node.IsDir {
 			elem = elem + " (recursive)"
 		}
 		delQ := c.view.NewDeleteQ(elem)
+		deleted := func() {
+			// Remove from injected cache if present
+			c.removeInjected(val.node)
+			c.cancelDetails()
+			c.view.Details.Clear()
+			c.updateList()
+		}
 		delQ.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-			if buttonLabel == "ok" {
-				if !val.node.IsDir {
-					err = c.model.Del(val.node.Name)
-				} else {
-					err = c.model.DelDir(val.node.Name)
-				}
-				if err != nil {
-					c.view.Pages.RemovePage("modal")
-					c.error("Error deleting node", err, false)
-					return
-				}
-				// Remove from injected cache if present
-				c.removeInjected(val.node)
-				c.view.Details.Clear()
-				c.updateList()
-			}
 			c.view.Pages.RemovePage("modal")
+			if buttonLabel != "ok" {
+				return
+			}
+			if val.node.IsDir {
+				c.delDir(val.node.Name, deleted)
+				return
+			}
+			if err := c.model.Del(c.ctx, val.node.Name); err != nil {
+				c.error("Error deleting node", err, false)
+				return
+			}
+			deleted()
 		})
 		c.view.Pages.AddPage("modal", c.view.ModalEdit(delQ, 20, 7), true, true)
 	}
 	return nil
 }
 
+// delDir deletes the directory dir and everything below it behind a
+// progress modal; deleted runs once it is gone.
+func (c *Controller) delDir(dir string, deleted func()) {
+	c.withProgress(fmt.Sprintf("Deleting %s", dir), func(ctx context.Context, progress func(done, total int)) error {
+		return c.model.DelDir(ctx, dir, progress)
+	}, func(err error) {
+		var de *model.DeleteError
+		switch {
+		case errors.As(err, &de):
+			c.cancelDetails()
+			c.view.Details.Clear()
+			c.updateList()
+			c.keyReport(stoppedTitle("Delete", err), fmt.Sprintf("%s\n\nThe remaining keys are still under %s. Already deleted:",
+				de.Error(), dir), de.Deleted)
+		case errors.Is(err, context.Canceled):
+			c.info("Delete cancelled", "No key was deleted")
+		case err != nil:
+			c.error("Error deleting node", err, false)
+		default:
+			deleted()
+		}
+	})
+}
+
 func (c *Controller) create() *tcell.EventKey {
+	if c.readOnly() {
+		return nil
+	}
+	if !c.model.CanWriteUnder(c.currentDir) {
+		c.error("Permission denied", fmt.Errorf("%w: no write access below %s", model.ErrPermissionDenied, c.currentDir), false)
+		return nil
+	}
 	pos := 0
-	var err error
 	createForm := c.view.NewCreateForm(fmt.Sprintf("Create Node: %s", c.currentDir))
 	createForm.AddButton("Save", func() {
 		node := strings.TrimSpace(createForm.GetFormItem(0).(*tview.InputField).GetText())
 		value := createForm.GetFormItem(1).(*tview.InputField).GetText()
-		isDir := createForm.GetFormItem(2).(*tview.Checkbox).IsChecked()
+		ttlText := createForm.GetFormItem(2).(*tview.InputField).GetText()
+		isDir := createForm.GetFormItem(3).(*tview.Checkbox).IsChecked()
 		if node == "" || strings.Contains(node, "/") {
 			c.view.Pages.RemovePage("modal")
 			c.error("Invalid name", fmt.Errorf("name must be non-empty and must not contain '/'"), false)
 			return
 		}
+		ttl, err := parseTTL(ttlText)
+		if err != nil {
+			c.view.Pages.RemovePage("modal")
+			c.error("Invalid TTL", err, false)
+			return
+		}
 		if node != "" {
-			log.Debugf("Creating Node: name: %s, isDir: %t, value: %s", node, isDir, value)
+			log.Debugf("Creating Node: name: %s, isDir: %t, ttl: %d, value: %s", node, isDir, ttl, value)
 			full := normAbs(c.currentDir + node)
 			if !isDir {
-				err = c.model.Set(full, value)
+				err = c.model.SetWithTTL(c.ctx, full, value, ttl)
 			} else {
-				err = c.model.MkDir(full)
+				err = c.model.MkDirWithTTL(c.ctx, full, ttl)
 			}
 			if err != nil {
 				c.view.Pages.RemovePage("modal")
@@ -752,7 +1224,7 @@ func (c *Controller) create() *tcell.EventKey {
 	createForm.AddButton("Quit", func() {
 		c.view.Pages.RemovePage("modal")
 	})
-	c.view.Pages.AddPage("modal", c.view.ModalEdit(createForm, 60, 11), true, true)
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(createForm, 60, 13), true, true)
 	return nil
 }
 
@@ -770,27 +1242,35 @@ func (c *Controller) edit() *tcell.EventKey {
 	if val, ok := c.currentNodes[mapKey]; ok {
 		// Edit file (value)
 		if !val.node.IsDir {
+			if err := c.loadValue(val); err != nil {
+				c.error("Cannot read value", err, false)
+				return nil
+			}
 			editValueForm := c.view.NewEditValueForm(fmt.Sprintf("Edit: %s", val.node.Name), val.node.Value)
+			session := newEditSession(val.node)
 			editValueForm.AddButton("Save", func() {
-				value := editValueForm.GetFormItem(0).(*tview.InputField).GetText()
+				input := editValueForm.GetFormItem(0).(*tview.InputField)
+				value := input.GetText()
 				log.Debugf("Editing Node Value: name: %s, value: %s", val.node.Name, value)
-				err = c.model.Set(val.node.Name, value)
+				saved := func() {
+					// If underscore, refresh injected value (path unchanged)
+					if strings.HasPrefix(baseOf(val.node.Name), "_") {
+						nd := &model.Node{Name: val.node.Name, IsDir: false, Value: value, ClusterId: val.node.ClusterId}
+						c.injectNode(nd)
+					}
+					ordered := c.updateList()
+					fs := strings.FieldsFunc(val.node.Name, splitFunc)
+					target := displayName(fs[len(fs)-1], false)
+					pos = c.getPosition(target, ordered) + 1
+					c.view.Pages.RemovePage("modal")
+					c.view.List.SetCurrentItem(pos)
+				}
+				err = c.save(session, value, saved, func(fresh string) { input.SetText(fresh) })
 				if err != nil {
 					c.view.Pages.RemovePage("modal")
 					c.error(fmt.Errorf("Failed to edit %s: %w", val.node.Name, err).Error(), err, false)
 					return
 				}
-				// If underscore, refresh injected value (path unchanged)
-				if strings.HasPrefix(baseOf(val.node.Name), "_") {
-					nd := &model.Node{Name: val.node.Name, IsDir: false, Value: value, ClusterId: val.node.ClusterId}
-					c.injectNode(nd)
-				}
-				ordered := c.updateList()
-				fs := strings.FieldsFunc(val.node.Name, splitFunc)
-				target := displayName(fs[len(fs)-1], false)
-				pos = c.getPosition(target, ordered) + 1
-				c.view.Pages.RemovePage("modal")
-				c.view.List.SetCurrentItem(pos)
 			})
 			editValueForm.AddButton("Quit", func() {
 				c.view.Pages.RemovePage("modal")
@@ -816,26 +1296,17 @@ func (c *Controller) edit() *tcell.EventKey {
 				c.view.Pages.RemovePage("modal")
 				return
 			}
-			if nd, err2 := c.model.Get(newPath); err2 == nil && nd != nil {
-				c.view.Pages.RemovePage("modal")
-				c.error("Target exists", fmt.Errorf("%q already exists; choose a different name", newPath), false)
-				return
-			}
 			log.Debugf("Renaming directory: %s -> %s", oldPath, newPath)
-			err = c.model.RenameDir(oldPath, newPath)
-			if err != nil {
-				c.view.Pages.RemovePage("modal")
-				c.error("Failed to rename folder", err, false)
-				return
-			}
-			// Update injected cache if underscore involved
-			if strings.HasPrefix(curBase, "_") || strings.HasPrefix(newName, "_") {
-				c.reinjectRename(oldPath, newPath, true, val.node.ClusterId, "")
-			}
-			ordered := c.updateList()
-			pos = c.getPosition(newName+"/", ordered) + 1
 			c.view.Pages.RemovePage("modal")
-			c.view.List.SetCurrentItem(pos)
+			c.move(oldPath, newPath, true, func() {
+				// Update injected cache if underscore involved
+				if strings.HasPrefix(curBase, "_") || strings.HasPrefix(newName, "_") {
+					c.reinjectRename(oldPath, newPath, val.node)
+				}
+				ordered := c.updateList()
+				pos = c.getPosition(newName+"/", ordered) + 1
+				c.view.List.SetCurrentItem(pos)
+			})
 		})
 		editDirForm.AddButton("Quit", func() {
 			c.view.Pages.RemovePage("modal")
@@ -846,6 +1317,9 @@ func (c *Controller) edit() *tcell.EventKey {
 }
 
 func (c *Controller) rename() *tcell.EventKey {
+	if c.readOnly() {
+		return nil
+	}
 	if c.view.List.GetItemCount() == 0 {
 		return nil
 	}
@@ -856,7 +1330,7 @@ func (c *Controller) rename() *tcell.EventKey {
 		return nil
 	}
 	val, ok := c.currentNodes[mapKey]
-	if !ok {
+	if !ok || c.writeDenied(val.node) {
 		return nil
 	}
 
@@ -882,37 +1356,21 @@ func (c *Controller) rename() *tcell.EventKey {
 			c.view.Pages.RemovePage("modal")
 			return
 		}
-		// Refuse to overwrite an existing key or directory silently.
-		if nd, err := c.model.Get(newPath); err == nil && nd != nil {
-			c.view.Pages.RemovePage("modal")
-			c.error("Target exists", fmt.Errorf("%q already exists; choose a different name", newPath), false)
-			return
-		}
-		var err error
-		if val.node.IsDir {
-			log.Debugf("Renaming directory: %s -> %s", oldPath, newPath)
-			err = c.model.RenameDir(oldPath, newPath)
-		} else {
-			log.Debugf("Renaming key: %s -> %s", oldPath, newPath)
-			err = c.model.RenameKey(oldPath, newPath)
-		}
-		if err != nil {
-			c.view.Pages.RemovePage("modal")
-			c.error("Failed to rename", err, false)
-			return
-		}
-		// Update injected cache if underscore-prefixed names are involved
-		if strings.HasPrefix(curBase, "_") || strings.HasPrefix(newName, "_") {
-			c.reinjectRename(oldPath, newPath, val.node.IsDir, val.node.ClusterId, val.node.Value)
-		}
-		ordered := c.updateList()
-		target := newName
-		if val.node.IsDir {
-			target = newName + "/"
-		}
-		pos := c.getPosition(target, ordered) + 1
+		// Existing targets are never overwritten without confirmation (see move).
 		c.view.Pages.RemovePage("modal")
-		c.view.List.SetCurrentItem(pos)
+		c.move(oldPath, newPath, val.node.IsDir, func() {
+			// Update injected cache if underscore-prefixed names are involved
+			if strings.HasPrefix(curBase, "_") || strings.HasPrefix(newName, "_") {
+				c.reinjectRename(oldPath, newPath, val.node)
+			}
+			ordered := c.updateList()
+			target := newName
+			if val.node.IsDir {
+				target = newName + "/"
+			}
+			pos := c.getPosition(target, ordered) + 1
+			c.view.List.SetCurrentItem(pos)
+		})
 	})
 	renameForm.AddButton("Cancel", func() {
 		c.view.Pages.RemovePage("modal")
@@ -922,6 +1380,9 @@ func (c *Controller) rename() *tcell.EventKey {
 }
 
 func (c *Controller) editMultiline() *tcell.EventKey {
+	if c.readOnly() {
+		return nil
+	}
 	if c.view.List.GetItemCount() == 0 {
 		return nil
 	}
@@ -932,7 +1393,7 @@ func (c *Controller) editMultiline() *tcell.EventKey {
 		return nil
 	}
 	val, ok := c.currentNodes[mapKey]
-	if !ok {
+	if !ok || c.writeDenied(val.node) {
 		return nil
 	}
 	if val.node.IsDir {
@@ -940,35 +1401,59 @@ func (c *Controller) editMultiline() *tcell.EventKey {
 		return c.edit()
 	}
 
-	title := fmt.Sprintf(" Edit (multiline): %s ", val.node.Name)
-	ta := c.view.NewMultilineEditor(title, val.node.Value)
+	if err := c.loadValue(val); err != nil {
+		c.error("Cannot read value", err, false)
+		return nil
+	}
+	ttl, err := c.model.RemainingTTL(c.ctx, val.node)
+	if err != nil {
+		log.Debugf("TTL of %s: %v", val.node.Name, err)
+	}
+	ta := c.view.NewMultilineEditor(editorTitle(val.node.Name, ttl), val.node.Value)
+	session := newEditSession(val.node)
+
+	// saveDone closes the editor after value was written
+	saveDone := func(value string) {
+		if strings.HasPrefix(baseOf(val.node.Name), "_") {
+			nd := &model.Node{Name: val.node.Name, IsDir: false, Value: value, ClusterId: val.node.ClusterId}
+			c.injectNode(nd)
+		}
+		c.view.CloseEditor()
+		ordered := c.updateList()
+		base := displayName(baseOf(val.node.Name), false)
+		pos := c.getPosition(base, ordered) + 1
+		c.view.List.SetCurrentItem(pos)
+		// Refresh details panel with mapKey
+		i := c.view.List.GetCurrentItem()
+		_, mk := c.view.List.GetItemText(i)
+		c.fillDetails(strings.TrimSpace(mk))
+	}
 
 	// Inside editor:
 	//   Ctrl+S = save
+	//   Ctrl+G = save under a lease (v3)
 	//   Esc / Ctrl+Q = cancel
 	ta.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
 		switch ev.Key() {
 		case tcell.KeyCtrlS:
 			value := ta.GetText()
 			log.Debugf("Multiline save: %s (%d bytes)", val.node.Name, len(value))
-			if err := c.model.Set(val.node.Name, value); err != nil {
+			saved := func() { saveDone(value) }
+			if err := c.save(session, value, saved, func(fresh string) { ta.SetText(fresh, false) }); err != nil {
 				c.view.CloseEditor()
 				c.error("Failed to save value", err, false)
-				return nil
-			}
-			if strings.HasPrefix(baseOf(val.node.Name), "_") {
-				nd := &model.Node{Name: val.node.Name, IsDir: false, Value: value, ClusterId: val.node.ClusterId}
-				c.injectNode(nd)
 			}
-			c.view.CloseEditor()
-			ordered := c.updateList()
-			base := displayName(baseOf(val.node.Name), false)
-			pos := c.getPosition(base, ordered) + 1
-			c.view.List.SetCurrentItem(pos)
-			// Refresh details panel with mapKey
-			i := c.view.List.GetCurrentItem()
-			_, mk := c.view.List.GetItemText(i)
-			c.fillDetails(strings.TrimSpace(mk))
+			return nil
+
+		case tcell.KeyCtrlG:
+			value := ta.GetText()
+			c.saveWithLease(session, value, func() { saveDone(value) })
+			return nil
+
+		case tcell.KeyCtrlT:
+			c.editTTL(session, func(ttl int64) {
+				c.view.RetitleEditor(ta, editorTitle(val.node.Name, ttl))
+			})
 			return nil
 
 		case tcell.KeyEsc, tcell.KeyCtrlQ:
@@ -982,54 +1467,121 @@ func (c *Controller) editMultiline() *tcell.EventKey {
 	return nil
 }
 
-// export prompts for a filename and writes all non-directory keys in the
-// current directory to a JSON file as {"key": "value", ...}.
+// export prompts for a format and filename and writes all non-directory
+// keys in the current directory using the chosen exporter.
 func (c *Controller) export() *tcell.EventKey {
-	defaultPath := "export.json"
+	formats := export.Names()
+	defaultPath := "export"
 	if home, err := os.UserHomeDir(); err == nil {
-		defaultPath = home + "/export.json"
+		defaultPath = home + "/export"
 	}
-	inp := c.view.NewExportInput(c.currentDir, defaultPath)
-	inp.SetDoneFunc(func(key tcell.Key) {
-		defer c.view.Pages.RemovePage("modal")
-		if key != tcell.KeyEnter {
-			return
+	first, _ := export.Get(formats[0])
+	ext := func(format string) string {
+		if e, err := export.Get(format); err == nil {
+			return e.Ext()
 		}
-		filename := strings.TrimSpace(inp.GetText())
+		return ""
+	}
+	form := c.view.NewExportInput(c.currentDir, defaultPath+first.Ext(), formats, ext)
+	form.AddButton("Export", func() {
+		filename := strings.TrimSpace(form.GetFormItem(0).(*tview.InputField).GetText())
+		_, format := form.GetFormItem(1).(*tview.DropDown).GetCurrentOption()
+		c.view.Pages.RemovePage("modal")
 		if filename == "" {
 			return
 		}
-
-		data, err := c.model.Export(c.currentDir)
+		exp, err := export.Get(format)
 		if err != nil {
 			c.error("Export failed", err, false)
 			return
 		}
 
-		raw, err := json.MarshalIndent(data, "", "  ")
-		if err != nil {
-			c.error("Export failed", err, false)
+		dir := c.currentDir
+		var data map[string]string
+		c.withProgress(fmt.Sprintf("Exporting %s", dir), func(ctx context.Context, progress func(done, total int)) (err error) {
+			data, err = c.model.Export(ctx, dir, progress)
+			return err
+		}, func(err error) {
+			if errors.Is(err, context.Canceled) {
+				c.info("Export cancelled", fmt.Sprintf("%s was not written", filename))
+				return
+			}
+			if err != nil {
+				c.error("Export failed", err, false)
+				return
+			}
+
+			var buf bytes.Buffer
+			opts := export.Options{Root: normAbs(dir), Protocol: c.model.ProtocolVersion()}
+			if err := exp.Write(&buf, data, opts); err != nil {
+				c.error("Export failed", err, false)
+				return
+			}
+			if err := os.WriteFile(filename, buf.Bytes(), 0o600); err != nil {
+				c.error("Cannot write file", fmt.Errorf("%s: %w", filename, err), false)
+				return
+			}
+			c.info("Exported", fmt.Sprintf("Saved %d keys to %s (%s)", len(data), filename, format))
+		})
+	})
+	form.AddButton("Cancel", func() {
+		c.view.Pages.RemovePage("modal")
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(form, 70, 9), true, true)
+	return nil
+}
+
+// revision prompts for a revision to browse the keyspace at (v3 only).
+// An empty value or 0 returns to the latest data.
+func (c *Controller) revision() *tcell.EventKey {
+	head, err := c.model.HeadRevision(c.ctx)
+	if err != nil {
+		c.error("Revision browsing unavailable", err, false)
+		return nil
+	}
+	inp := c.view.NewRevisionInput(head, c.model.PinnedRevision())
+	inp.SetDoneFunc(func(key tcell.Key) {
+		defer c.view.Pages.RemovePage("modal")
+		if key != tcell.KeyEnter {
 			return
 		}
-		if err := os.WriteFile(filename, raw, 0o600); err != nil {
-			c.error("Cannot write file", fmt.Errorf("%s: %w", filename, err), false)
+		raw := strings.TrimSpace(inp.GetText())
+		var rev int64
+		if raw != "" {
+			rev, err = strconv.ParseInt(raw, 10, 64)
+			if err != nil || rev < 0 {
+				c.error("Invalid revision", fmt.Errorf("%q is not a revision number", raw), false)
+				return
+			}
+		}
+		if err := c.model.PinRevision(c.ctx, rev); err != nil {
+			switch {
+			case errors.Is(err, model.ErrCompacted):
+				c.error("Revision compacted", fmt.Errorf("%w (head is %d)", err, head), false)
+			case errors.Is(err, model.ErrFutureRevision):
+				c.error("No such revision", fmt.Errorf("%w (head is %d)", err, head), false)
+			default:
+				c.error("Cannot pin revision", err, false)
+			}
 			return
 		}
-		c.info("Exported", fmt.Sprintf("Saved %d keys to %s", len(data), filename))
+		c.updateHeader()
+		c.refresh()
 	})
 	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 60, 5), true, true)
 	return nil
 }
 
 func (c *Controller) error(header string, err error, fatal bool) {
+	pages := c.view.ActivePages()
 	errMsg := c.view.NewErrorMessageQ(header, err.Error())
 	errMsg.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-		c.view.Pages.RemovePage("modal")
+		pages.RemovePage("modal")
 		if fatal {
 			c.view.App.Stop()
 		}
 	})
-	c.view.Pages.AddPage("modal", c.view.ModalEdit(errMsg, 60, 7), true, true)
+	pages.AddPage("modal", c.view.ModalEdit(errMsg, 60, 7), true, true)
 }
 
 func valueStats(v string) (bytes int, lines int, printable bool) {
@@ -1054,82 +1606,88 @@ func depthOf(path string) int {
 func (c *Controller) jump() *tcell.EventKey {
 	inp := c.view.NewJump()
 	inp.SetDoneFunc(func(key tcell.Key) {
-		defer c.view.Pages.RemovePage("modal")
+		c.view.Pages.RemovePage("modal")
 		if key != tcell.KeyEnter {
 			return
 		}
+		c.jumpTo(strings.TrimSpace(inp.GetText()))
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 60, 5), true, true)
+	return nil
+}
 
-		raw := strings.TrimSpace(inp.GetText())
-		if raw == "" {
-			return
-		}
+// jumpTo opens the directory raw (absolute, or relative to currentDir) or
+// the directory holding the key raw with the cursor on it. A trailing '/'
+// insists on a directory.
+func (c *Controller) jumpTo(raw string) {
+	if raw == "" {
+		return
+	}
 
-		isDirHint := strings.HasSuffix(raw, "/")
-		var target string
-		if strings.HasPrefix(raw, "/") {
-			target = normAbs(raw)
+	isDirHint := strings.HasSuffix(raw, "/")
+	var target string
+	if strings.HasPrefix(raw, "/") {
+		target = normAbs(raw)
+	} else {
+		cur := normAbs(c.currentDir)
+		if cur != "/" {
+			target = normAbs(cur + "/" + raw)
 		} else {
-			cur := normAbs(c.currentDir)
-			if cur != "/" {
-				target = normAbs(cur + "/" + raw)
-			} else {
-				target = normAbs("/" + raw)
-			}
+			target = normAbs("/" + raw)
 		}
+	}
 
-		nd, err := c.model.Get(target)
-		if err != nil {
-			c.error("Not found", fmt.Errorf("%s", target), false)
-			return
-		}
+	nd, err := c.model.Get(c.ctx, target)
+	if err != nil {
+		c.error("Not found", fmt.Errorf("%s", target), false)
+		return
+	}
 
-		if isDirHint && !nd.IsDir {
-			c.error("Not a folder", fmt.Errorf("%s", target), false)
-			return
-		}
+	if isDirHint && !nd.IsDir {
+		c.error("Not a folder", fmt.Errorf("%s", target), false)
+		return
+	}
 
-		c.injectNode(nd)
+	c.injectNode(nd)
 
-		if nd.IsDir {
-			c.currentDir = normAbs(nd.Name) + "/"
-			c.Cd(c.currentDir)
-			return
-		}
+	if nd.IsDir {
+		c.currentDir = normAbs(nd.Name) + "/"
+		c.Cd(c.currentDir)
+		return
+	}
 
-		parent := parentOf(nd.Name)
-		base := baseOf(nd.Name)
-		if !strings.HasSuffix(parent, "/") {
-			parent += "/"
-		}
-		c.currentDir = parent
-		ordered := c.updateList()
+	parent := parentOf(nd.Name)
+	base := baseOf(nd.Name)
+	if !strings.HasSuffix(parent, "/") {
+		parent += "/"
+	}
+	c.currentDir = parent
+	ordered := c.updateList()
+	c.subscribe()
 
-		findIndex := func(name string, list []string) int {
-			for i, v := range list {
-				if v == name {
-					return i
-				}
+	findIndex := func(name string, list []string) int {
+		for i, v := range list {
+			if v == name {
+				return i
 			}
-			return -1
 		}
+		return -1
+	}
 
-		if pos := findIndex(base, ordered); pos >= 0 {
-			c.view.List.SetCurrentItem(pos + 1) // for [..]
-			i := c.view.List.GetCurrentItem()
-			_, mk := c.view.List.GetItemText(i)
-			c.fillDetails(strings.TrimSpace(mk))
-			return
-		}
-		if pos := findIndex(base+"/", ordered); pos >= 0 {
-			c.view.List.SetCurrentItem(pos + 1)
-			i := c.view.List.GetCurrentItem()
-			_, mk := c.view.List.GetItemText(i)
-			c.fillDetails(strings.TrimSpace(mk))
-			return
-		}
+	if pos := findIndex(base, ordered); pos >= 0 {
+		c.view.List.SetCurrentItem(pos + 1) // for [..]
+		i := c.view.List.GetCurrentItem()
+		_, mk := c.view.List.GetItemText(i)
+		c.fillDetails(strings.TrimSpace(mk))
+		return
+	}
+	if pos := findIndex(base+"/", ordered); pos >= 0 {
+		c.view.List.SetCurrentItem(pos + 1)
+		i := c.view.List.GetCurrentItem()
+		_, mk := c.view.List.GetItemText(i)
+		c.fillDetails(strings.TrimSpace(mk))
+		return
+	}
 
-		c.error("Not found", fmt.Errorf("%s", target), false)
-	})
-	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 60, 5), true, true)
-	return nil
+	c.error("Not found", fmt.Errorf("%s", target), false)
 }
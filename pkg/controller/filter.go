@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// filterMode is how the filter bar matches names in the listing.
+type filterMode int
+
+const (
+	filterFuzzy filterMode = iota
+	filterSubstring
+	filterRegex
+	filterModes // number of modes
+)
+
+func (m filterMode) String() string {
+	switch m {
+	case filterSubstring:
+		return "substring"
+	case filterRegex:
+		return "regex"
+	}
+	return "fuzzy"
+}
+
+// listFilter narrows the listing of dir to the entries whose name matches
+// text. Fuzzy and substring matching ignore case.
+type listFilter struct {
+	dir  string
+	text string
+	mode filterMode
+	re   *regexp.Regexp // text compiled in regex mode; nil if invalid
+}
+
+// set changes the filter text and mode.
+func (f *listFilter) set(text string, mode filterMode) {
+	f.text, f.mode, f.re = text, mode, nil
+	if mode == filterRegex {
+		f.re, _ = regexp.Compile(text)
+	}
+}
+
+// invalid reports a regular expression that does not compile; the listing
+// stays unfiltered meanwhile.
+func (f *listFilter) invalid() bool {
+	return f.mode == filterRegex && f.text != "" && f.re == nil
+}
+
+// active reports whether the filter narrows the listing of dir.
+func (f *listFilter) active(dir string) bool {
+	return f.text != "" && f.dir == dir && !f.invalid()
+}
+
+// match reports whether name passes the filter, and which of its runes
+// matched.
+func (f *listFilter) match(name string) ([]bool, bool) {
+	runes := []rune(name)
+	hit := make([]bool, len(runes))
+
+	if f.mode == filterRegex {
+		locs := f.re.FindAllStringIndex(name, -1)
+		if locs == nil {
+			return nil, false
+		}
+		i := 0
+		for b := range name {
+			for _, l := range locs {
+				if b >= l[0] && b < l[1] {
+					hit[i] = true
+					break
+				}
+			}
+			i++
+		}
+		return hit, true
+	}
+
+	lower := func(rs []rune) []rune {
+		out := make([]rune, len(rs))
+		for i, r := range rs {
+			out[i] = unicode.ToLower(r)
+		}
+		return out
+	}
+	s, pat := lower(runes), lower([]rune(f.text))
+
+	if f.mode == filterSubstring {
+		for i := 0; i+len(pat) <= len(s); i++ {
+			if string(s[i:i+len(pat)]) == string(pat) {
+				for j := range pat {
+					hit[i+j] = true
+				}
+				return hit, true
+			}
+		}
+		return nil, false
+	}
+
+	// fuzzy: the pattern's runes appear in order, not necessarily adjacent
+	j := 0
+	for i := 0; i < len(s) && j < len(pat); i++ {
+		if s[i] == pat[j] {
+			hit[i] = true
+			j++
+		}
+	}
+	return hit, j == len(pat)
+}
+
+// highlight escapes name for the list and underlines the runes in hit.
+func highlight(name string, hit []bool) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && hit[j] == hit[i] {
+			j++
+		}
+		seg := tview.Escape(string(runes[i:j]))
+		if hit[i] {
+			seg = "[::bu]" + seg + "[::-]"
+		}
+		sb.WriteString(seg)
+		i = j
+	}
+	return sb.String()
+}
+
+// openFilter shows the filter bar for the current directory (f). Typing
+// narrows the listing, Tab switches the mode, Enter returns to the
+// narrowed list and Esc restores the full one.
+func (c *Controller) openFilter() *tcell.EventKey {
+	if c.filter.dir != c.currentDir {
+		c.filter.dir = c.currentDir
+		c.filter.set("", c.filter.mode)
+	}
+	c.view.Filter.SetText(c.filter.text)
+	c.filterLabel()
+	c.view.ShowFilter()
+	return nil
+}
+
+// setupFilter installs the handlers of the filter bar.
+func (c *Controller) setupFilter() {
+	c.view.Filter.SetChangedFunc(func(text string) {
+		if text == c.filter.text {
+			return
+		}
+		c.setFilter(text, c.filter.mode)
+	})
+	c.view.Filter.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyTab:
+			c.setFilter(c.filter.text, (c.filter.mode+1)%filterModes)
+		case tcell.KeyBacktab:
+			c.setFilter(c.filter.text, (c.filter.mode+filterModes-1)%filterModes)
+		case tcell.KeyEnter:
+			if c.filter.text == "" {
+				c.view.HideFilter()
+				return
+			}
+			c.view.App.SetFocus(c.view.List)
+		case tcell.KeyEscape:
+			c.clearFilter()
+		}
+	})
+}
+
+// setFilter re-renders the listing through the new filter, keeping the
+// cursor on the same entry while it is still shown.
+func (c *Controller) setFilter(text string, mode filterMode) {
+	mk := c.selectedMapKey()
+	c.filter.dir = c.currentDir
+	c.filter.set(text, mode)
+	c.filterLabel()
+	c.renderList()
+
+	pos := c.findMapKey(mk)
+	if pos < 0 {
+		pos = min(1, c.view.List.GetItemCount()-1) // first match, or [..]
+	}
+	c.view.List.SetCurrentItem(pos)
+	c.fillDetails(c.selectedMapKey())
+}
+
+// clearFilter closes the filter bar and shows the full listing again.
+func (c *Controller) clearFilter() {
+	c.setFilter("", c.filter.mode)
+	c.view.Filter.SetText("")
+	c.view.HideFilter()
+}
+
+func (c *Controller) filterLabel() {
+	label := fmt.Sprintf("Filter (%s, Tab=mode): ", c.filter.mode)
+	if c.filter.invalid() {
+		label = fmt.Sprintf("Filter (%s, invalid): ", c.filter.mode)
+	}
+	c.view.SetFilterLabel(label, c.filter.invalid())
+}
+
+// selectedMapKey is the mapKey of the entry under the cursor, ".." on
+// [..], or "" for an empty list.
+func (c *Controller) selectedMapKey() string {
+	if c.view.List.GetItemCount() == 0 {
+		return ""
+	}
+	_, mk := c.view.List.GetItemText(c.view.List.GetCurrentItem())
+	return strings.TrimSpace(mk)
+}
@@ -0,0 +1,90 @@
+package controller
+
+import "testing"
+
+// marks renders the runes of name that hit, e.g. "a_c_" for "abcd" with
+// b and d missed.
+func marks(name string, hit []bool) string {
+	out := []rune(name)
+	for i := range out {
+		if !hit[i] {
+			out[i] = '_'
+		}
+	}
+	return string(out)
+}
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		mode filterMode
+		text string
+		name string
+		want string // runes that matched, the rest as '_'; "" for no match
+	}{
+		{filterFuzzy, "dbh", "db-host", "db_h___"},
+		{filterFuzzy, "DBH", "db-host", "db_h___"},
+		{filterFuzzy, "hd", "db-host", ""},
+		{filterFuzzy, "gö", "größe", "g_ö__"},
+		{filterFuzzy, "x", "", ""},
+
+		{filterSubstring, "host", "db-host", "___host"},
+		{filterSubstring, "HoSt", "db-host", "___host"},
+		{filterSubstring, "o", "foo", "_o_"},
+		{filterSubstring, "ÖSS", "größe", ""},
+		{filterSubstring, "Öß", "größe", "__öß_"},
+		{filterSubstring, "dbh", "db-host", ""},
+
+		{filterRegex, `^db`, "db-host", "db_____"},
+		{filterRegex, `o`, "foo", "_oo"},
+		{filterRegex, `[0-9]+$`, "node42", "____42"},
+		{filterRegex, `DB`, "db-host", ""},
+		{filterRegex, `ö.`, "größe", "__öß_"},
+		{filterRegex, `☃`, "a☃b☃", "_☃_☃"},
+	}
+	for _, tt := range tests {
+		var f listFilter
+		f.set(tt.text, tt.mode)
+		hit, ok := f.match(tt.name)
+		got := ""
+		if ok {
+			got = marks(tt.name, hit)
+		}
+		if got != tt.want {
+			t.Errorf("%s %q on %q = %q, want %q", tt.mode, tt.text, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterInvalid(t *testing.T) {
+	var f listFilter
+	f.dir = "/app"
+	f.set("(", filterRegex)
+	if !f.invalid() || f.active("/app") {
+		t.Errorf("regex %q: invalid %v, active %v", f.text, f.invalid(), f.active("/app"))
+	}
+	f.set("(", filterSubstring)
+	if f.invalid() || !f.active("/app") || f.active("/other") {
+		t.Errorf("substring %q: invalid %v, active %v", f.text, f.invalid(), f.active("/app"))
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name string
+		hit  []bool
+		want string
+	}{
+		{"", nil, ""},
+		{"abc", []bool{false, false, false}, "abc"},
+		{"abc", []bool{true, true, true}, "[::bu]abc[::-]"},
+		{"db-host", []bool{true, true, false, true, false, false, false}, "[::bu]db[::-]-[::bu]h[::-]ost"},
+		{"größe", []bool{false, false, true, true, false}, "gr[::bu]öß[::-]e"},
+		// tags in names are escaped, not interpreted
+		{"[red]x", []bool{false, false, false, false, false, true}, "[red[][::bu]x[::-]"},
+	}
+	for _, tt := range tests {
+		if got := highlight(tt.name, tt.hit); got != tt.want {
+			t.Errorf("highlight(%q, %v) = %q, want %q", tt.name, tt.hit, got, tt.want)
+		}
+	}
+}
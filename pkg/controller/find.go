@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nexusriot/etcd-walker/pkg/model"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+)
+
+// findLimit caps how many hits one search lists; the scan stops there.
+const findLimit = 1000
+
+var (
+	findModes   = []string{model.SearchSubstring.String(), model.SearchGlob.String(), model.SearchRegex.String()}
+	findTargets = []string{model.SearchPathsAndValues.String(), model.SearchPaths.String(), model.SearchValues.String()}
+)
+
+// find asks for a pattern and searches every key below the current
+// directory for it (Ctrl+F).
+func (c *Controller) find() *tcell.EventKey {
+	dir := withTrailing(c.currentDir)
+	form := c.view.NewFindForm(dir, findModes, findTargets)
+	form.AddButton("Find", func() {
+		mode, _ := form.GetFormItem(1).(*tview.DropDown).GetCurrentOption()
+		target, _ := form.GetFormItem(2).(*tview.DropDown).GetCurrentOption()
+		q := model.SearchQuery{
+			Pattern:    form.GetFormItem(0).(*tview.InputField).GetText(),
+			Mode:       model.SearchMode(mode),
+			Target:     model.SearchTarget(target),
+			IgnoreCase: form.GetFormItem(3).(*tview.Checkbox).IsChecked(),
+		}
+		if q.Pattern == "" {
+			return
+		}
+		c.view.Pages.RemovePage("modal")
+		c.findResults(dir, q)
+	})
+	form.AddButton("Cancel", func() {
+		c.view.Pages.RemovePage("modal")
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(form, 70, 13), true, true)
+	return nil
+}
+
+// findResults runs q below dir in the background and lists the hits as
+// they come in. Enter jumps to the highlighted key; Esc closes the list
+// and stops the scan.
+func (c *Controller) findResults(dir string, q model.SearchQuery) {
+	fv := c.view.NewFindView(fmt.Sprintf("Find %q (%s, %s) below %s", q.Pattern, q.Mode, q.Target, dir))
+	ctx, cancel := context.WithCancel(c.ctx)
+
+	var hits []model.SearchHit
+	show := func(i int) {
+		fv.Value.Clear()
+		if i < 0 || i >= len(hits) {
+			return
+		}
+		h := hits[i]
+		var where []string
+		if h.InPath {
+			where = append(where, "path")
+		}
+		if h.InValue {
+			where = append(where, "value")
+		}
+		fmt.Fprintf(fv.Value, "[::b]%s[::-]\nmatched in %s\n\n", tview.Escape(h.Key), strings.Join(where, " and "))
+		switch {
+		case q.Target == model.SearchPaths:
+			fmt.Fprint(fv.Value, "[yellow]Values were not searched[-]")
+		case !utf8.ValidString(h.Value):
+			fmt.Fprint(fv.Value, "[yellow]Binary / non-UTF8 value (preview suppressed)[-]")
+		default:
+			fmt.Fprint(fv.Value, tview.Escape(h.Value))
+		}
+		fv.Value.ScrollToBeginning()
+	}
+	fv.Results.SetChangedFunc(func(i int, _, _ string, _ rune) { show(i) })
+
+	status := func(text string) {
+		fv.Status.SetText(fmt.Sprintf(" %d match(es)  %s", len(hits), text))
+	}
+	status("scanning …")
+
+	fv.Results.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyEsc:
+			cancel()
+			c.view.Pages.RemovePage("find")
+			return nil
+		case tcell.KeyEnter:
+			i := fv.Results.GetCurrentItem()
+			if i >= len(hits) {
+				return nil
+			}
+			cancel()
+			c.view.Pages.RemovePage("find")
+			c.jumpTo(hits[i].Key)
+			return nil
+		}
+		return ev
+	})
+	c.view.Pages.AddPage("find", fv, true, true)
+
+	go func() {
+		var scanned, total int
+		limited := false // only touched on the UI goroutine
+		err := c.model.Search(ctx, dir, q, func(found []model.SearchHit, done, all int) {
+			scanned, total = done, all
+			c.view.App.QueueUpdateDraw(func() {
+				for _, h := range found {
+					if len(hits) == findLimit {
+						limited = true
+						cancel()
+						break
+					}
+					hits = append(hits, h)
+					// the first item added fires the changed func
+					fv.Results.AddItem(tview.Escape(h.Key), "", 0, nil)
+				}
+				status(fmt.Sprintf("scanning … %d / %d keys", done, all))
+			})
+		})
+		cancel()
+		c.view.App.QueueUpdateDraw(func() {
+			switch {
+			case limited:
+				status(fmt.Sprintf("stopped at the first %d after %d of %d keys", findLimit, scanned, total))
+			case errors.Is(err, context.Canceled):
+				// closed before the scan ended
+			case err != nil:
+				log.Debugf("find %q below %s: %v", q.Pattern, dir, err)
+				status(fmt.Sprintf("[red]scan failed after %d keys: %s[-]", scanned, tview.Escape(err.Error())))
+			default:
+				status(fmt.Sprintf("%d keys scanned", scanned))
+			}
+		})
+	}()
+}
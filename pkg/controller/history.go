@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nexusriot/etcd-walker/pkg/model"
+	"github.com/nexusriot/etcd-walker/pkg/util/diff"
+	"github.com/nexusriot/etcd-walker/pkg/view"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+)
+
+// historyLimit caps how many versions are fetched for one key; every
+// version costs a round trip.
+const historyLimit = 200
+
+// history opens the version timeline of the selected key. Space marks the
+// diff base (the newest version by default), d diffs the highlighted
+// version against it and r writes the highlighted value back as a new
+// version.
+func (c *Controller) history() *tcell.EventKey {
+	nd := c.selectedNode()
+	if nd == nil {
+		return nil
+	}
+	if nd.IsDir {
+		c.error("History", fmt.Errorf("history is available for keys only"), false)
+		return nil
+	}
+
+	var (
+		versions  []model.KeyVersion
+		truncated bool
+	)
+	c.busy(fmt.Sprintf("Loading history of %s …", nd.Name), func(ctx context.Context) (err error) {
+		versions, truncated, err = c.model.History(ctx, nd.Name, historyLimit)
+		return err
+	}, func(err error) {
+		switch {
+		case errors.Is(err, context.Canceled):
+		case err != nil:
+			c.error("Cannot load history", err, false)
+		default:
+			c.showHistory(nd, versions, truncated)
+		}
+	})
+	return nil
+}
+
+// showHistory lists the loaded versions of nd, newest first.
+func (c *Controller) showHistory(nd *model.Node, versions []model.KeyVersion, truncated bool) {
+	hv := c.view.NewHistoryView(nd.Name)
+	base := 0
+
+	label := func(i int) string {
+		v := versions[i]
+		mark := "  "
+		if i == base {
+			mark = "* "
+		}
+		l := fmt.Sprintf("%srev %-8d v%-4d %7d B", mark, v.ModRevision, v.Version, len(v.Value))
+		if i == 0 {
+			l += "  [current]"
+		}
+		return l
+	}
+	for i := range versions {
+		hv.Versions.AddItem(label(i), fmt.Sprintf("%d", i), 0, nil)
+	}
+	if truncated {
+		hv.Versions.AddItem("  … older versions compacted or over limit", "-", 0, nil)
+	}
+
+	show := func(i int) {
+		hv.Content.Clear()
+		if i < 0 || i >= len(versions) {
+			return
+		}
+		v := versions[i]
+		fmt.Fprintf(hv.Content, "[::b]rev %d, version %d[::-]\n\n", v.ModRevision, v.Version)
+		if !utf8.ValidString(v.Value) {
+			fmt.Fprintf(hv.Content, "[yellow]Binary / non-UTF8 value (preview suppressed)[-]\n")
+			return
+		}
+		fmt.Fprint(hv.Content, tview.Escape(v.Value))
+		hv.Content.ScrollToBeginning()
+	}
+	hv.Versions.SetChangedFunc(func(i int, _, _ string, _ rune) { show(i) })
+	show(0)
+
+	hv.Versions.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		i := hv.Versions.GetCurrentItem()
+		switch ev.Key() {
+		case tcell.KeyEsc:
+			c.view.Pages.RemovePage("history")
+			return nil
+		case tcell.KeyRune:
+			if i >= len(versions) {
+				return nil
+			}
+			switch ev.Rune() {
+			case ' ':
+				old := base
+				base = i
+				hv.Versions.SetItemText(old, label(old), fmt.Sprintf("%d", old))
+				hv.Versions.SetItemText(base, label(base), fmt.Sprintf("%d", base))
+				return nil
+			case 'd':
+				showDiff(hv, versions, base, i)
+				return nil
+			case 'r':
+				c.restoreVersion(nd, versions[i], versions[0].ModRevision)
+				return nil
+			}
+		}
+		return ev
+	})
+
+	c.view.Pages.AddPage("history", hv, true, true)
+}
+
+// showDiff renders the change from the older of the two versions to the newer.
+func showDiff(hv *view.HistoryView, versions []model.KeyVersion, a, b int) {
+	if a < b {
+		a, b = b, a // larger index is older
+	}
+	from, to := versions[a], versions[b]
+	hv.Content.Clear()
+	fmt.Fprintf(hv.Content, "[::b]rev %d → rev %d[::-]\n\n", from.ModRevision, to.ModRevision)
+	if a == b {
+		fmt.Fprintf(hv.Content, "[yellow]Same version; mark another one with Space.[-]\n")
+		return
+	}
+	if !utf8.ValidString(from.Value) || !utf8.ValidString(to.Value) {
+		fmt.Fprintf(hv.Content, "[yellow]Binary / non-UTF8 value (diff suppressed)[-]\n")
+		return
+	}
+	writeDiff(hv.Content, diff.Lines(from.Value, to.Value))
+	hv.Content.ScrollToBeginning()
+}
+
+// writeDiff prints diff lines with +/- markers in green/red.
+func writeDiff(tv *tview.TextView, lines []diff.Line) {
+	var sb strings.Builder
+	for _, l := range lines {
+		text := tview.Escape(l.Text)
+		switch l.Op {
+		case diff.Insert:
+			sb.WriteString("[green]+ " + text + "[-]\n")
+		case diff.Delete:
+			sb.WriteString("[red]- " + text + "[-]\n")
+		default:
+			sb.WriteString("  " + text + "\n")
+		}
+	}
+	fmt.Fprint(tv, sb.String())
+}
+
+// restoreVersion writes v's value back as a new version. The write only
+// goes through while the key is still at head, the revision the timeline
+// was loaded at, and keeps the key's lease.
+func (c *Controller) restoreVersion(nd *model.Node, v model.KeyVersion, head int64) {
+	if c.readOnly() || c.writeDenied(nd) {
+		return
+	}
+	confirm := c.view.NewConfirmQ(fmt.Sprintf("Restore %s to the value of rev %d?", nd.Name, v.ModRevision))
+	confirm.SetDoneFunc(func(_ int, buttonLabel string) {
+		c.view.Pages.RemovePage("modal")
+		if buttonLabel != "ok" {
+			return
+		}
+		log.Debugf("Restoring %s from rev %d", nd.Name, v.ModRevision)
+		if err := c.model.SetIfUnchanged(c.ctx, nd.Name, v.Value, head); err != nil {
+			if errors.Is(err, model.ErrConflict) {
+				err = fmt.Errorf("%s changed after the history was loaded; reopen it and try again", nd.Name)
+			}
+			c.error("Restore failed", err, false)
+			return
+		}
+		// Reload both the listing and the timeline, which now has a new head.
+		c.view.Pages.RemovePage("history")
+		c.refresh()
+		c.history()
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(confirm, 50, 7), true, true)
+}
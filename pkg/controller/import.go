@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nexusriot/etcd-walker/pkg/model"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+)
+
+// importPolicies lists the conflict policies in model.ConflictPolicy order.
+var importPolicies = []string{
+	model.ImportSkip.String(),
+	model.ImportOverwrite.String(),
+	model.ImportFail.String(),
+}
+
+// importJSON reads a flat {"key": "value"} file written by export and
+// imports it after showing a dry-run summary.
+func (c *Controller) importJSON() *tcell.EventKey {
+	if c.readOnly() {
+		return nil
+	}
+	defaultPath := "export.json"
+	if home, err := os.UserHomeDir(); err == nil {
+		defaultPath = home + "/export.json"
+	}
+	inp := c.view.NewImportInput(c.currentDir, defaultPath)
+	inp.SetDoneFunc(func(key tcell.Key) {
+		c.view.Pages.RemovePage("modal")
+		if key != tcell.KeyEnter {
+			return
+		}
+		filename := strings.TrimSpace(inp.GetText())
+		if filename == "" {
+			return
+		}
+		raw, err := os.ReadFile(filename)
+		if err != nil {
+			c.error("Cannot read file", err, false)
+			return
+		}
+		var data map[string]string
+		if err := json.Unmarshal(raw, &data); err != nil {
+			c.error("Invalid import file", fmt.Errorf("%s: expected a flat {\"key\": \"value\"} object: %w", filename, err), false)
+			return
+		}
+		c.importForm(filename, data)
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 60, 5), true, true)
+	return nil
+}
+
+func (c *Controller) importForm(filename string, data map[string]string) {
+	from := model.CommonDir(data)
+	form := c.view.NewImportForm(fmt.Sprintf("Import %d keys from %s", len(data), filename), importPolicies, from, from)
+	form.AddButton("Preview", func() {
+		policy, _ := form.GetFormItem(0).(*tview.DropDown).GetCurrentOption()
+		opts := model.ImportOptions{
+			Policy:     model.ConflictPolicy(policy),
+			FromPrefix: strings.TrimSpace(form.GetFormItem(1).(*tview.InputField).GetText()),
+			ToPrefix:   strings.TrimSpace(form.GetFormItem(2).(*tview.InputField).GetText()),
+			DryRun:     true,
+		}
+		c.view.Pages.RemovePage("modal")
+
+		var rep *model.ImportReport
+		c.busy(fmt.Sprintf("Comparing %d keys …", len(data)), func(ctx context.Context) (err error) {
+			rep, err = c.model.Import(ctx, data, opts, nil)
+			return err
+		}, func(err error) {
+			switch {
+			case errors.Is(err, context.Canceled):
+			case errors.Is(err, model.ErrImportConflict):
+				c.error("Import refused", fmt.Errorf("%s\n\nConflicting: %s", err, listKeys(rep.Skipped)), false)
+			case err != nil:
+				c.error("Import failed", err, false)
+			case len(rep.Create)+len(rep.Update) == 0:
+				c.info("Nothing to import", importSummary(rep))
+			default:
+				c.confirmImport(filename, data, opts, rep)
+			}
+		})
+	})
+	form.AddButton("Cancel", func() {
+		c.view.Pages.RemovePage("modal")
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(form, 70, 11), true, true)
+}
+
+// confirmImport shows the dry-run report and writes data once confirmed.
+func (c *Controller) confirmImport(filename string, data map[string]string, opts model.ImportOptions, preview *model.ImportReport) {
+	confirm := c.view.NewConfirmQ(fmt.Sprintf("Import into %s?\n\n%s", importTarget(opts), importSummary(preview)))
+	confirm.SetDoneFunc(func(_ int, buttonLabel string) {
+		c.view.Pages.RemovePage("modal")
+		if buttonLabel != "ok" {
+			return
+		}
+		opts.DryRun = false
+		log.Debugf("Importing %s (policy %s, %q -> %q)", filename, opts.Policy, opts.FromPrefix, opts.ToPrefix)
+		var rep *model.ImportReport
+		c.withProgress(fmt.Sprintf("Importing %s", filename), func(ctx context.Context, progress func(done, total int)) (err error) {
+			rep, err = c.model.Import(ctx, data, opts, progress)
+			return err
+		}, func(err error) {
+			c.updateList()
+			switch {
+			case errors.Is(err, model.ErrImportConflict) && len(rep.Conflicts) > 0:
+				c.keyReport("Import finished with conflicts", fmt.Sprintf("%s\n\n%s\n\nChanged by someone else meanwhile, not written:",
+					err, importSummary(rep)), rep.Conflicts)
+			case errors.Is(err, context.Canceled):
+				c.error(stoppedTitle("Import", err), err, false)
+			case err != nil:
+				c.error("Import failed", err, false)
+			default:
+				c.info("Imported", importSummary(rep))
+			}
+		})
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(confirm, 60, 12), true, true)
+}
+
+func importSummary(rep *model.ImportReport) string {
+	s := fmt.Sprintf("create %d, update %d, unchanged %d, skipped %d",
+		len(rep.Create), len(rep.Update), len(rep.Unchanged), len(rep.Skipped))
+	if len(rep.Conflicts) > 0 {
+		s += fmt.Sprintf(", conflicts %d", len(rep.Conflicts))
+	}
+	return s
+}
+
+// importTarget describes where an import writes to.
+func importTarget(opts model.ImportOptions) string {
+	if opts.FromPrefix == "" {
+		return "the original paths"
+	}
+	return normAbs(opts.ToPrefix)
+}
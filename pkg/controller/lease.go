@@ -0,0 +1,355 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nexusriot/etcd-walker/pkg/model"
+	"github.com/nexusriot/etcd-walker/pkg/view"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+)
+
+// leaseBrowser is the state of the open lease view (Ctrl+A).
+type leaseBrowser struct {
+	lv      *view.LeaseView
+	leases  []model.Lease
+	fetched time.Time // when leases were read; TTLs count down from here
+	stop    chan struct{}
+}
+
+func (lb *leaseBrowser) selected() *model.Lease {
+	i := lb.lv.Leases.GetCurrentItem()
+	if i < 0 || i >= len(lb.leases) {
+		return nil
+	}
+	return &lb.leases[i]
+}
+
+// label shows a lease with its TTL counted down locally between reloads.
+func (lb *leaseBrowser) label(l model.Lease) string {
+	return leaseLabel(l, time.Since(lb.fetched))
+}
+
+// leaseLabel shows a lease read elapsed ago, with its TTL counted down.
+func leaseLabel(l model.Lease, elapsed time.Duration) string {
+	left := l.TTL
+	if left >= 0 {
+		left -= int64(elapsed / time.Second)
+		if left < 0 {
+			left = 0
+		}
+	}
+	ttl := fmt.Sprintf("%ds / %ds", left, l.GrantedTTL)
+	if left <= 0 {
+		ttl = "[red]expired[-]"
+	} else if left*5 < l.GrantedTTL {
+		ttl = "[yellow]" + ttl + "[-]"
+	}
+	return fmt.Sprintf("%x  %s  (%d keys)", l.ID, ttl, len(l.Keys))
+}
+
+// leases opens the lease browser.
+func (c *Controller) leases() *tcell.EventKey {
+	lb := &leaseBrowser{lv: c.view.NewLeaseView(), stop: make(chan struct{})}
+	c.loadLeases(lb, func() { c.openLeases(lb) })
+	return nil
+}
+
+// openLeases shows the lease browser once its first load is done.
+func (c *Controller) openLeases(lb *leaseBrowser) {
+	lb.lv.Leases.SetChangedFunc(func(int, string, string, rune) { c.showLeaseKeys(lb) })
+	lb.lv.Keys.SetSelectedFunc(func(_ int, _, key string, _ rune) {
+		c.closeLeases(lb)
+		c.jumpTo(key)
+	})
+
+	lb.lv.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyEsc:
+			c.closeLeases(lb)
+			return nil
+		case tcell.KeyTab, tcell.KeyBacktab:
+			if lb.lv.Keys.HasFocus() {
+				c.view.App.SetFocus(lb.lv.Leases)
+			} else {
+				c.view.App.SetFocus(lb.lv.Keys)
+			}
+			return nil
+		case tcell.KeyRune:
+		default:
+			return ev
+		}
+
+		if lb.lv.Keys.HasFocus() {
+			if ev.Rune() == 'd' {
+				c.detachLeaseKey(lb)
+				return nil
+			}
+			return ev
+		}
+		switch ev.Rune() {
+		case 'r':
+			c.loadLeases(lb, nil)
+		case 'g':
+			c.grantLease(lb)
+		case 'a':
+			c.attachLeaseKey(lb)
+		case 'x':
+			c.revokeLease(lb)
+		default:
+			return ev
+		}
+		return nil
+	})
+
+	c.view.Pages.AddPage("leases", lb.lv, true, true)
+	go c.tickLeases(lb)
+}
+
+func (c *Controller) closeLeases(lb *leaseBrowser) {
+	close(lb.stop)
+	c.view.Pages.RemovePage("leases")
+}
+
+// tickLeases redraws the TTL countdown once a second until the view closes.
+func (c *Controller) tickLeases(lb *leaseBrowser) {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-lb.stop:
+			return
+		case <-t.C:
+			c.view.App.QueueUpdateDraw(func() {
+				for i, l := range lb.leases {
+					if i < lb.lv.Leases.GetItemCount() {
+						lb.lv.Leases.SetItemText(i, lb.label(l), "")
+					}
+				}
+			})
+		}
+	}
+}
+
+// loadLeases (re)reads all leases in the background, keeping the cursor
+// on the same lease, and calls loaded (if not nil) once they are shown.
+func (c *Controller) loadLeases(lb *leaseBrowser, loaded func()) {
+	var keep int64
+	if l := lb.selected(); l != nil {
+		keep = l.ID
+	}
+	var leases []model.Lease
+	c.busy("Reading leases …", func(ctx context.Context) (err error) {
+		leases, err = c.model.Leases(ctx)
+		return err
+	}, func(err error) {
+		switch {
+		case errors.Is(err, context.Canceled):
+			return
+		case err != nil:
+			c.error("Cannot list leases", err, false)
+			return
+		}
+		lb.leases, lb.fetched = leases, time.Now()
+
+		lb.lv.Leases.Clear()
+		cur := 0
+		for i, l := range leases {
+			lb.lv.Leases.AddItem(lb.label(l), "", 0, nil)
+			if l.ID == keep {
+				cur = i
+			}
+		}
+		if len(leases) > 0 {
+			lb.lv.Leases.SetCurrentItem(cur)
+		}
+		c.showLeaseKeys(lb)
+		if loaded != nil {
+			loaded()
+		}
+	})
+}
+
+func (c *Controller) showLeaseKeys(lb *leaseBrowser) {
+	lb.lv.Keys.Clear()
+	if l := lb.selected(); l != nil {
+		for _, k := range l.Keys {
+			lb.lv.Keys.AddItem(tview.Escape(k), k, 0, nil) // secondary: raw key
+		}
+	}
+}
+
+func (c *Controller) grantLease(lb *leaseBrowser) {
+	if c.readOnly() {
+		return
+	}
+	inp := c.view.NewLeaseGrantInput()
+	inp.SetDoneFunc(func(key tcell.Key) {
+		c.view.Pages.RemovePage("modal")
+		if key != tcell.KeyEnter {
+			return
+		}
+		ttl, err := strconv.ParseInt(strings.TrimSpace(inp.GetText()), 10, 64)
+		if err != nil || ttl <= 0 {
+			c.error("Invalid TTL", fmt.Errorf("TTL must be a positive number of seconds"), false)
+			return
+		}
+		id, err := c.model.GrantLease(c.ctx, ttl)
+		if err != nil {
+			c.error("Cannot grant lease", err, false)
+			return
+		}
+		log.Debugf("Granted lease %x (%ds)", id, ttl)
+		c.loadLeases(lb, func() {
+			for i, l := range lb.leases {
+				if l.ID == id {
+					lb.lv.Leases.SetCurrentItem(i)
+				}
+			}
+		})
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 40, 5), true, true)
+}
+
+// attachLeaseKey attaches a key (by default the one selected in the main
+// list) to the selected lease, keeping its value.
+func (c *Controller) attachLeaseKey(lb *leaseBrowser) {
+	l := lb.selected()
+	if l == nil || c.readOnly() {
+		return
+	}
+	def := ""
+	if nd := c.selectedNode(); nd != nil && !nd.IsDir {
+		def = nd.Name
+	}
+	inp := c.view.NewLeaseAttachInput(l.ID, def)
+	inp.SetDoneFunc(func(key tcell.Key) {
+		c.view.Pages.RemovePage("modal")
+		if key != tcell.KeyEnter {
+			return
+		}
+		k := normAbs(strings.TrimSpace(inp.GetText()))
+		nd, err := c.model.Get(c.ctx, k)
+		if err == nil && nd.IsDir {
+			err = fmt.Errorf("%s is a directory", k)
+		}
+		if err == nil {
+			err = c.model.AttachLease(c.ctx, k, l.ID, nd.Revision())
+		}
+		if err != nil {
+			c.error("Cannot attach key", err, false)
+			return
+		}
+		c.loadLeases(lb, nil)
+		c.refresh()
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 60, 5), true, true)
+}
+
+func (c *Controller) detachLeaseKey(lb *leaseBrowser) {
+	if lb.lv.Keys.GetItemCount() == 0 || c.readOnly() {
+		return
+	}
+	_, k := lb.lv.Keys.GetItemText(lb.lv.Keys.GetCurrentItem())
+	confirm := c.view.NewConfirmQ(fmt.Sprintf("Detach %s from its lease?\nThe key will no longer expire.", k))
+	confirm.SetDoneFunc(func(_ int, buttonLabel string) {
+		c.view.Pages.RemovePage("modal")
+		if buttonLabel != "ok" {
+			return
+		}
+		nd, err := c.model.Get(c.ctx, k)
+		if err == nil {
+			err = c.model.AttachLease(c.ctx, k, 0, nd.Revision())
+		}
+		if err != nil {
+			c.error("Cannot detach key", err, false)
+			return
+		}
+		c.loadLeases(lb, func() { c.view.App.SetFocus(lb.lv.Leases) })
+		c.refresh()
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(confirm, 60, 8), true, true)
+}
+
+func (c *Controller) revokeLease(lb *leaseBrowser) {
+	l := lb.selected()
+	if l == nil || c.readOnly() {
+		return
+	}
+	msg := fmt.Sprintf("Revoke lease %x?", l.ID)
+	if n := len(l.Keys); n > 0 {
+		msg += fmt.Sprintf("\nThis deletes %d attached key(s): %s", n, listKeys(l.Keys))
+	}
+	confirm := c.view.NewConfirmQ(msg)
+	confirm.SetDoneFunc(func(_ int, buttonLabel string) {
+		c.view.Pages.RemovePage("modal")
+		if buttonLabel != "ok" {
+			return
+		}
+		if err := c.model.RevokeLease(c.ctx, l.ID); err != nil {
+			c.error("Cannot revoke lease", err, false)
+			return
+		}
+		log.Debugf("Revoked lease %x", l.ID)
+		c.loadLeases(lb, nil)
+		c.refresh()
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(confirm, 60, 12), true, true)
+}
+
+// saveWithLease lets the user pick a lease, or none, for the key open in
+// the editor and saves value under it (Ctrl+G in the editor). Like save it
+// only writes if the key is still at the session revision.
+func (c *Controller) saveWithLease(s *editSession, value string, saved func()) {
+	pages := c.view.ActivePages()
+	var leases []model.Lease
+	c.busy("Reading leases …", func(ctx context.Context) (err error) {
+		leases, err = c.model.Leases(ctx)
+		return err
+	}, func(err error) {
+		switch {
+		case errors.Is(err, context.Canceled):
+			return
+		case err != nil:
+			c.error("Cannot list leases", err, false)
+			return
+		}
+		items := []string{"no lease (never expires)"}
+		cur := 0
+		for i, l := range leases {
+			items = append(items, leaseLabel(l, 0))
+			if l.ID == s.node.Lease {
+				cur = i + 1
+			}
+		}
+		picker := c.view.NewPicker(fmt.Sprintf("Save %s under lease", s.node.Name), items)
+		picker.SetCurrentItem(cur)
+		picker.SetSelectedFunc(func(i int, _, _ string, _ rune) {
+			pages.RemovePage("modal-lease")
+			var id int64
+			if i > 0 {
+				id = leases[i-1].ID
+			}
+			log.Debugf("save %s under lease %x (expected rev %d)", s.node.Name, id, s.rev)
+			err := c.model.SetWithLease(c.ctx, s.node.Name, value, id, s.rev)
+			if errors.Is(err, model.ErrConflict) {
+				err = fmt.Errorf("%s changed since the editor loaded it; save with Ctrl+S to resolve the conflict first", s.node.Name)
+			}
+			if err != nil {
+				c.error("Failed to save value", err, false)
+				return
+			}
+			saved()
+		})
+		picker.SetDoneFunc(func() {
+			pages.RemovePage("modal-lease")
+		})
+		pages.AddPage("modal-lease", c.view.ModalEdit(picker, 60, min(len(items), 15)+2), true, true)
+	})
+}
@@ -0,0 +1,354 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nexusriot/etcd-walker/pkg/model"
+	"github.com/nexusriot/etcd-walker/pkg/view"
+	log "github.com/sirupsen/logrus"
+)
+
+// maintenanceActions are the entries of the maintenance menu, in the
+// order a NOSPACE recovery runs them.
+var maintenanceActions = []string{
+	"Compact revision history",
+	"Defragment selected member",
+	"Defragment all members",
+	"Alarms: list and disarm",
+	"Compare KV hashes across members",
+}
+
+// maintenanceMenu offers the v3 maintenance operations from the cluster
+// dashboard (m). Compaction, defragmentation and disarming are writes and
+// ask for confirmation with the DB sizes they are about to change.
+func (c *Controller) maintenanceMenu(cv *view.ClusterView) {
+	menu := c.view.NewPicker("Maintenance", maintenanceActions)
+	menu.SetSelectedFunc(func(i int, _, _ string, _ rune) {
+		c.view.Pages.RemovePage("modal")
+		switch i {
+		case 0:
+			c.compact(cv)
+		case 1:
+			c.defragment(cv, selectedMemberID(cv))
+		case 2:
+			c.defragment(cv, "")
+		case 3:
+			c.alarms(cv)
+		case 4:
+			c.hashKV()
+		}
+	})
+	menu.SetDoneFunc(func() {
+		c.view.Pages.RemovePage("modal")
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(menu, 44, len(maintenanceActions)+2), true, true)
+}
+
+// selectedMemberID is the ID of the member selected in the dashboard, ""
+// if there is none.
+func selectedMemberID(cv *view.ClusterView) string {
+	row, _ := cv.Members.GetSelection()
+	if row < 1 {
+		return ""
+	}
+	if cell := cv.Members.GetCell(row, 1); cell != nil {
+		return cell.Text
+	}
+	return ""
+}
+
+// report shows a multi-line result.
+func (c *Controller) report(header, text string) {
+	m := c.view.NewInfoModal(header, "\n\n"+text, "ok")
+	m.SetDoneFunc(func(int, string) {
+		c.view.Pages.RemovePage("modal-info")
+	})
+	h := strings.Count(text, "\n") + 8
+	c.view.Pages.AddPage("modal-info", c.view.ModalEdit(m, 72, h), true, true)
+}
+
+// dbSizes lists the database size of every member, or only of the member
+// with ID id when it is set.
+func dbSizes(cs *model.ClusterStatus, id string) string {
+	var sb strings.Builder
+	for _, mb := range cs.Members {
+		if id != "" && mb.ID != id {
+			continue
+		}
+		if mb.Status == nil {
+			fmt.Fprintf(&sb, "%s: size unknown\n", mb.Name)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s: %s, %s in use\n", mb.Name, byteSize(mb.Status.DBSize), byteSize(mb.Status.DBSizeInUse))
+	}
+	return sb.String()
+}
+
+// sizeChange compares the database sizes before and after an operation.
+func sizeChange(before, after *model.ClusterStatus, id string) string {
+	now := map[string]*model.MemberStatus{}
+	for _, mb := range after.Members {
+		now[mb.ID] = mb.Status
+	}
+	var sb strings.Builder
+	for _, mb := range before.Members {
+		if id != "" && mb.ID != id {
+			continue
+		}
+		a, b := now[mb.ID], mb.Status
+		if a == nil || b == nil {
+			fmt.Fprintf(&sb, "%s: size unknown\n", mb.Name)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s: %s → %s (in use %s → %s)\n", mb.Name,
+			byteSize(b.DBSize), byteSize(a.DBSize), byteSize(b.DBSizeInUse), byteSize(a.DBSizeInUse))
+	}
+	return sb.String()
+}
+
+// compact asks for a revision (the head by default), shows the current DB
+// sizes and compacts; the sizes in use afterwards show what was freed.
+func (c *Controller) compact(cv *view.ClusterView) {
+	if c.readOnly() {
+		return
+	}
+	m := c.model
+	var head int64
+	c.busy("Reading the head revision …", func(ctx context.Context) (err error) {
+		head, err = m.HeadRevision(ctx)
+		return err
+	}, func(err error) {
+		switch {
+		case errors.Is(err, context.Canceled):
+		case err != nil:
+			c.error("Compaction unavailable", err, false)
+		default:
+			c.compactTo(cv, head)
+		}
+	})
+}
+
+// compactTo asks for the revision to compact to, at most head.
+func (c *Controller) compactTo(cv *view.ClusterView, head int64) {
+	m := c.model
+	inp := c.view.NewCompactInput(head)
+	inp.SetDoneFunc(func(key tcell.Key) {
+		c.view.Pages.RemovePage("modal")
+		if key != tcell.KeyEnter {
+			return
+		}
+		raw := strings.TrimSpace(inp.GetText())
+		rev, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || rev < 1 || rev > head {
+			c.error("Invalid revision", fmt.Errorf("%q is not a revision between 1 and %d", raw, head), false)
+			return
+		}
+
+		var before *model.ClusterStatus
+		c.busy("Reading DB sizes …", func(ctx context.Context) (err error) {
+			before, err = m.ClusterStatus(ctx)
+			return err
+		}, func(err error) {
+			if err != nil {
+				c.error("Cannot read cluster status", err, false)
+				return
+			}
+			text := fmt.Sprintf("Compact to revision %d (head %d)?\n\nEvery version older than %d is discarded for good.\n\nDB size now:\n%s",
+				rev, head, rev, dbSizes(before, ""))
+			c.confirm(text, 72, strings.Count(text, "\n")+7, func() {
+				var after *model.ClusterStatus
+				c.busy(fmt.Sprintf("Compacting to revision %d …", rev), func(ctx context.Context) (err error) {
+					log.Debugf("compacting to revision %d", rev)
+					if err = m.Compact(ctx, rev); err != nil {
+						return err
+					}
+					after, err = m.ClusterStatus(ctx)
+					return err
+				}, func(err error) {
+					if err != nil {
+						c.error("Compaction failed", err, false)
+						return
+					}
+					c.loadClusterStatus(cv)
+					c.report("Compacted", fmt.Sprintf("History before revision %d is gone; defragment to shrink the files.\n\n%s",
+						rev, sizeChange(before, after, "")))
+				})
+			})
+		})
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 60, 5), true, true)
+}
+
+// defragment defragments the member with ID id, or every member when id
+// is empty: followers first, the leader last, one at a time so the
+// cluster keeps a quorum.
+func (c *Controller) defragment(cv *view.ClusterView, id string) {
+	if c.readOnly() {
+		return
+	}
+	m := c.model
+	var before *model.ClusterStatus
+	c.busy("Reading DB sizes …", func(ctx context.Context) (err error) {
+		before, err = m.ClusterStatus(ctx)
+		return err
+	}, func(err error) {
+		if err != nil {
+			c.error("Cannot read cluster status", err, false)
+			return
+		}
+		var targets []model.Member
+		for _, mb := range before.Members {
+			if (id == "" || mb.ID == id) && len(mb.ClientURLs) > 0 {
+				targets = append(targets, mb)
+			}
+		}
+		if len(targets) == 0 {
+			c.error("Nothing to defragment", fmt.Errorf("no reachable member selected"), false)
+			return
+		}
+		sort.SliceStable(targets, func(i, j int) bool {
+			return targets[j].ID == before.Leader && targets[i].ID != before.Leader
+		})
+
+		what := fmt.Sprintf("all %d members, one at a time", len(targets))
+		if id != "" {
+			what = fmt.Sprintf("%s (%s)", targets[0].Name, targets[0].ClientURLs[0])
+		}
+		text := fmt.Sprintf("Defragment %s?\n\nA member does not serve requests while it rewrites its database.\n\nDB size now (expected after: about the size in use):\n%s",
+			what, dbSizes(before, id))
+		c.confirm(text, 72, strings.Count(text, "\n")+7, func() {
+			var after *model.ClusterStatus
+			c.busy(fmt.Sprintf("Defragmenting %s …", what), func(ctx context.Context) error {
+				var errs []error
+				for _, mb := range targets {
+					if ctx.Err() != nil {
+						// cancelled: the members left keep their files
+						return errors.Join(append(errs, ctx.Err())...)
+					}
+					log.Debugf("defragmenting %s (%s)", mb.Name, mb.ClientURLs[0])
+					if err := m.Defragment(ctx, mb.ClientURLs[0]); err != nil {
+						errs = append(errs, fmt.Errorf("%s: %w", mb.Name, err))
+					}
+				}
+				var err error
+				after, err = m.ClusterStatus(ctx)
+				return errors.Join(append(errs, err)...)
+			}, func(err error) {
+				c.loadClusterStatus(cv)
+				if err != nil {
+					c.error("Defragmentation failed", err, false)
+					return
+				}
+				c.report("Defragmented", sizeChange(before, after, id))
+			})
+		})
+	})
+}
+
+// alarms lists the raised alarms and offers to disarm them.
+func (c *Controller) alarms(cv *view.ClusterView) {
+	m := c.model
+	var (
+		alarms []model.Alarm
+		names  = map[string]string{}
+	)
+	c.busy("Reading alarms …", func(ctx context.Context) (err error) {
+		if alarms, err = m.Alarms(ctx); err != nil {
+			return err
+		}
+		if cs, err := m.ClusterStatus(ctx); err == nil {
+			for _, mb := range cs.Members {
+				names[mb.ID] = mb.Name
+			}
+		}
+		return nil
+	}, func(err error) {
+		if err != nil {
+			c.error("Cannot read alarms", err, false)
+			return
+		}
+		if len(alarms) == 0 {
+			c.info("No alarms", "No member has raised an alarm.")
+			return
+		}
+		var sb strings.Builder
+		for _, a := range alarms {
+			member := a.MemberID
+			if n := names[a.MemberID]; n != "" {
+				member = fmt.Sprintf("%s (%s)", n, a.MemberID)
+			}
+			fmt.Fprintf(&sb, "%s: %s\n", member, a.Type)
+		}
+		if m.ReadOnly() {
+			// nothing to confirm: disarming would be refused
+			c.report("Raised alarms", sb.String()+"\nDisarming is disabled in read-only mode.")
+			return
+		}
+		text := fmt.Sprintf("Raised alarms:\n\n%s\nDisarm them all? NOSPACE comes straight back unless space was freed first (compact, then defragment).", sb.String())
+		c.confirm(text, 72, len(alarms)+11, func() {
+			c.busy("Disarming alarms …", m.DisarmAlarms, func(err error) {
+				c.loadClusterStatus(cv)
+				if err != nil {
+					c.error("Cannot disarm alarms", err, false)
+					return
+				}
+				c.info("Alarms disarmed", fmt.Sprintf("%d alarm(s) cleared.", len(alarms)))
+			})
+		})
+	})
+}
+
+// hashKV compares the members' keyspace hashes at the current revision.
+func (c *Controller) hashKV() {
+	m := c.model
+	var hashes []model.MemberHash
+	c.busy("Hashing the keyspace on every member …", func(ctx context.Context) (err error) {
+		hashes, err = m.HashKV(ctx, 0)
+		return err
+	}, func(err error) {
+		if err != nil {
+			c.error("Cannot compare hashes", err, false)
+			return
+		}
+		c.report("KV hashes", hashReport(hashes))
+	})
+}
+
+// hashReport lists the hashes; members only have to agree when they
+// compacted at the same revision, since the hash starts there.
+func hashReport(hashes []model.MemberHash) string {
+	var sb strings.Builder
+	compacted := map[int64]bool{}
+	sums := map[uint32]bool{}
+	failed := 0
+	for _, h := range hashes {
+		if h.Err != nil {
+			failed++
+			fmt.Fprintf(&sb, "%s: %s\n", h.Name, h.Err)
+			continue
+		}
+		compacted[h.CompactRevision] = true
+		sums[h.Hash] = true
+		fmt.Fprintf(&sb, "%s: %08x (compacted at %d)\n", h.Name, h.Hash, h.CompactRevision)
+	}
+	if len(hashes) > 0 {
+		fmt.Fprintf(&sb, "\nAt revision %d: ", hashes[0].Revision)
+	}
+	switch {
+	case len(compacted) > 1:
+		sb.WriteString("members compacted at different revisions, try again once compaction has caught up")
+	case len(sums) > 1:
+		sb.WriteString("MEMBERS DISAGREE, their data has diverged")
+	case failed > 0:
+		fmt.Fprintf(&sb, "%d member(s) could not be compared", failed)
+	default:
+		fmt.Fprintf(&sb, "all %d members agree", len(hashes))
+	}
+	return sb.String()
+}
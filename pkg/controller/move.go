@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nexusriot/etcd-walker/pkg/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxListed bounds how many keys a confirmation or report spells out.
+const maxListed = 5
+
+// move renames oldPath to newPath. The source is read in the background;
+// moves that would overwrite destination keys or that run as several
+// steps are confirmed first. done runs after a successful move.
+func (c *Controller) move(oldPath, newPath string, isDir bool, done func()) {
+	var plan *model.MovePlan
+	c.busy(fmt.Sprintf("Reading %s …", oldPath), func(ctx context.Context) (err error) {
+		plan, err = c.model.PlanMove(ctx, oldPath, newPath, isDir)
+		return err
+	}, func(err error) {
+		switch {
+		case errors.Is(err, context.Canceled):
+		case err != nil:
+			c.error("Failed to rename", err, false)
+		default:
+			c.runMove(plan, done)
+		}
+	})
+}
+
+// runMove confirms and carries out a planned move.
+func (c *Controller) runMove(plan *model.MovePlan, done func()) {
+	exec := func(overwrite bool) {
+		log.Debugf("Moving %s -> %s (%d keys, overwrite=%t)", plan.From, plan.To, plan.Keys, overwrite)
+		run := func(ctx context.Context, progress func(done, total int)) error {
+			return c.model.Move(ctx, plan, overwrite, progress)
+		}
+		finish := func(err error) {
+			var me *model.MoveError
+			switch {
+			case errors.As(err, &me) && len(me.Moved) > 0:
+				c.updateList()
+				c.keyReport(stoppedTitle("Rename", err), fmt.Sprintf("%s\n\nThe remaining keys are still under %s. Already moved:",
+					me.Error(), plan.From), me.Moved)
+			case errors.As(err, &me):
+				c.updateList()
+				c.keyReport(stoppedTitle("Rename", err), fmt.Sprintf("%s\n\nNothing was deleted from %s. Already copied:",
+					me.Error(), plan.From), me.Copied)
+			case errors.Is(err, context.Canceled):
+				c.info("Rename cancelled", "No key was moved")
+			case err != nil:
+				c.error("Failed to rename", err, false)
+			default:
+				done()
+			}
+		}
+		if !plan.Stepwise() {
+			finish(run(c.ctx, nil))
+			return
+		}
+		c.withProgress(fmt.Sprintf("Moving %s to %s", plan.From, plan.To), run, finish)
+	}
+
+	if !plan.Stepwise() && len(plan.Existing) == 0 {
+		exec(false)
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Move %d key(s) from %s to %s.", plan.Keys, plan.From, plan.To)
+	if plan.Stepwise() {
+		if plan.Batches > 0 {
+			fmt.Fprintf(&sb, "\n\nToo large for one transaction: runs as %d transactions. Each key moves atomically, the subtree as a whole does not.", plan.Batches)
+		} else {
+			sb.WriteString("\n\netcd v2 has no transactions: keys are copied, then the source is deleted.")
+		}
+	}
+	if len(plan.Existing) > 0 {
+		fmt.Fprintf(&sb, "\n\n%d existing key(s) will be OVERWRITTEN: %s", len(plan.Existing), listKeys(plan.Existing))
+	}
+
+	confirm := c.view.NewConfirmQ(sb.String())
+	confirm.SetDoneFunc(func(_ int, buttonLabel string) {
+		c.view.Pages.RemovePage("modal-move")
+		if buttonLabel == "ok" {
+			exec(len(plan.Existing) > 0)
+		}
+	})
+	c.view.Pages.AddPage("modal-move", c.view.ModalEdit(confirm, 70, 15), true, true)
+}
+
+// listKeys formats up to maxListed keys followed by a count of the rest.
+func listKeys(keys []string) string {
+	if len(keys) <= maxListed {
+		return strings.Join(keys, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(keys[:maxListed], ", "), len(keys)-maxListed)
+}
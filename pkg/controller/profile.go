@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nexusriot/etcd-walker/pkg/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// switchProfile lets the user pick another connection profile (Ctrl+G).
+func (c *Controller) switchProfile() *tcell.EventKey {
+	if len(c.profiles) < 2 {
+		c.info("No profiles", `add a "profiles" map to the config file to switch clusters here`)
+		return nil
+	}
+	names := make([]string, len(c.profiles))
+	cur := 0
+	for i, p := range c.profiles {
+		names[i] = p.Name
+		if p.Name == c.profile.Name {
+			cur = i
+		}
+	}
+
+	picker := c.view.NewProfilePicker(names, c.profile.Name)
+	picker.SetCurrentItem(cur)
+	picker.SetSelectedFunc(func(i int, _, _ string, _ rune) {
+		c.view.Pages.RemovePage("modal")
+		if c.profiles[i].Name == c.profile.Name {
+			return
+		}
+		c.connect(c.profiles[i])
+	})
+	picker.SetDoneFunc(func() {
+		c.view.Pages.RemovePage("modal")
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(picker, 40, len(names)+2), true, true)
+	return nil
+}
+
+// connect builds a model for p in the background and swaps it in. The
+// current connection stays usable until the new one is up, and is kept
+// if connecting fails or is cancelled.
+func (c *Controller) connect(p Profile) {
+	var m *model.Model
+	c.busy(fmt.Sprintf("Connecting to %s …", p.Name), func(ctx context.Context) (err error) {
+		m, err = model.NewModel(ctx, p.Options)
+		if cerr := ctx.Err(); cerr != nil {
+			// cancelled: a connection that got through anyway is dropped
+			if err == nil {
+				if err := m.Close(); err != nil {
+					log.Debugf("closing cancelled connection: %v", err)
+				}
+			}
+			return cerr
+		}
+		return err
+	}, func(err error) {
+		switch {
+		case errors.Is(err, context.Canceled):
+			return
+		case err != nil:
+			c.error(fmt.Sprintf("Cannot connect to %s", p.Name), err, false)
+			return
+		}
+		log.Debugf("switching to profile %s", p.Name)
+
+		c.unsubscribe()
+		old := c.model
+		c.model, c.opts, c.profile = m, p.Options, p
+		if err := old.Close(); err != nil {
+			log.Debugf("closing previous connection: %v", err)
+		}
+
+		c.currentDir = m.StartDir()
+		c.position = make(map[string]int)
+		c.injected = make(map[string]map[string]*model.Node)
+		c.cancelDetails()
+		c.view.Details.Clear()
+		c.Cd(c.currentDir)
+	})
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// busy runs work off the UI goroutine behind a wait message and then calls
+// done with its error on the UI goroutine. Esc cancels work's context;
+// done still runs, with whatever error work returns.
+func (c *Controller) busy(text string, work func(ctx context.Context) error, done func(err error)) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	pages := c.view.ActivePages()
+	c.waitCancellable(text, cancel)
+	go func() {
+		err := work(ctx)
+		cancel()
+		c.view.App.QueueUpdateDraw(func() {
+			pages.RemovePage("modal-wait")
+			done(err)
+		})
+	}()
+}
+
+// waitCancellable shows text on the "modal-wait" page of the active page
+// stack until it is removed; Esc calls cancel.
+func (c *Controller) waitCancellable(text string, cancel context.CancelFunc) *tview.Modal {
+	wait := c.view.NewWaitModal(text + "\n\n[Esc] cancel")
+	wait.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEsc {
+			cancel()
+			wait.SetText(text + "\n\ncancelling …")
+			return nil
+		}
+		return ev
+	})
+	c.view.ActivePages().AddPage("modal-wait", c.view.ModalEdit(wait, 60, 7), true, true)
+	return wait
+}
+
+// withProgress runs work off the UI goroutine behind a progress modal on
+// the "modal-progress" page. work reports the keys it has handled through
+// progress; Cancel or Esc cancels its ctx. done runs on the UI goroutine
+// with the error of work.
+func (c *Controller) withProgress(text string, work func(ctx context.Context, progress func(done, total int)) error, done func(err error)) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	pm := c.view.NewProgressModal(text)
+	pm.SetDoneFunc(func(int, string) {
+		cancel()
+		pm.SetCancelling()
+	})
+	c.view.Pages.AddPage("modal-progress", c.view.ModalEdit(pm, 64, 12), true, true)
+
+	var last time.Time
+	progress := func(n, total int) {
+		// a few frames a second are enough; the last one always shows
+		if n < total && time.Since(last) < 100*time.Millisecond {
+			return
+		}
+		last = time.Now()
+		c.view.App.QueueUpdateDraw(func() { pm.SetProgress(n, total) })
+	}
+	go func() {
+		err := work(ctx, progress)
+		cancel()
+		c.view.App.QueueUpdateDraw(func() {
+			c.view.Pages.RemovePage("modal-progress")
+			done(err)
+		})
+	}()
+}
+
+// keyReport tells what an operation that stopped part-way already did:
+// summary, then every one of keys. Esc closes it.
+func (c *Controller) keyReport(title, summary string, keys []string) {
+	tv := c.view.NewKeyReport(title)
+	fmt.Fprintf(tv, "%s\n\n", tview.Escape(summary))
+	for _, k := range keys {
+		fmt.Fprintln(tv, tview.Escape(k))
+	}
+	tv.ScrollToBeginning()
+	tv.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEsc {
+			c.view.Pages.RemovePage("modal-keys")
+			return nil
+		}
+		return ev
+	})
+	c.view.Pages.AddPage("modal-keys", tv, true, true)
+}
+
+// stoppedTitle names an operation that stopped part-way, telling a
+// cancellation from a failure.
+func stoppedTitle(what string, err error) string {
+	if errors.Is(err, context.Canceled) {
+		return what + " cancelled"
+	}
+	return what + " incomplete"
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+// saveSnapshot asks for a file name and streams a snapshot of the
+// cluster's database into it, showing progress. Reading the database is
+// not a write, so this works in read-only mode too. The file can be
+// browsed later with -snapshot.
+func (c *Controller) saveSnapshot() *tcell.EventKey {
+	name := fmt.Sprintf("etcd-snapshot-%s.db", time.Now().Format("20060102-150405"))
+	defaultPath := name
+	if home, err := os.UserHomeDir(); err == nil {
+		defaultPath = filepath.Join(home, name)
+	}
+	inp := c.view.NewSnapshotInput(defaultPath)
+	inp.SetDoneFunc(func(key tcell.Key) {
+		c.view.Pages.RemovePage("modal")
+		if key != tcell.KeyEnter {
+			return
+		}
+		filename := strings.TrimSpace(inp.GetText())
+		if filename == "" {
+			return
+		}
+		c.runSnapshot(filename)
+	})
+	c.view.Pages.AddPage("modal", c.view.ModalEdit(inp, 70, 5), true, true)
+	return nil
+}
+
+// runSnapshot saves the snapshot in the background; progress redraws are
+// throttled so a fast stream does not flood the event loop. Esc abandons
+// it and leaves no file behind.
+func (c *Controller) runSnapshot(filename string) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	wait := c.waitCancellable(fmt.Sprintf("Saving snapshot to %s …", filename), cancel)
+
+	m := c.model
+	go func() {
+		var last time.Time
+		progress := func(done, total int64) {
+			if time.Since(last) < 100*time.Millisecond {
+				return
+			}
+			last = time.Now()
+			text := fmt.Sprintf("Saving snapshot to %s\n\n%s\n\n[Esc] cancel", filename, progressBar(done, total))
+			c.view.App.QueueUpdateDraw(func() {
+				if ctx.Err() == nil {
+					wait.SetText(text)
+				}
+			})
+		}
+		start := time.Now()
+		n, err := m.SaveSnapshot(ctx, filename, progress)
+		cancel()
+		c.view.App.QueueUpdateDraw(func() {
+			c.view.Pages.RemovePage("modal-wait")
+			if errors.Is(err, context.Canceled) {
+				c.info("Snapshot cancelled", fmt.Sprintf("%s was not written", filename))
+				return
+			}
+			if err != nil {
+				c.error("Snapshot failed", err, false)
+				return
+			}
+			log.Debugf("snapshot of %s saved to %s (%d bytes)", m.Endpoint(), filename, n)
+			c.info("Snapshot saved", fmt.Sprintf("%s written to %s in %s",
+				byteSize(n), filename, time.Since(start).Round(100*time.Millisecond)))
+		})
+	}()
+}
+
+// progressBar renders done out of total as a text bar; an unknown total
+// only shows the byte count.
+func progressBar(done, total int64) string {
+	if total <= 0 {
+		return byteSize(done)
+	}
+	const width = 30
+	frac := min(float64(done)/float64(total), 1)
+	filled := int(frac * width)
+	return fmt.Sprintf("%s%s %3.0f%%  %s / %s",
+		strings.Repeat("█", filled), strings.Repeat("░", width-filled),
+		frac*100, byteSize(done), byteSize(total))
+}
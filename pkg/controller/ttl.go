@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+)
+
+// parseTTL reads a TTL in seconds; empty means none (0).
+func parseTTL(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	ttl, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("TTL must be a number of seconds, 0 or empty for none")
+	}
+	return ttl, nil
+}
+
+// editorTitle names the key being edited and, if it expires, when.
+func editorTitle(name string, ttl int64) string {
+	title := fmt.Sprintf(" Edit (multiline): %s ", name)
+	if ttl > 0 {
+		title += fmt.Sprintf("(expires in %ds) ", ttl)
+	}
+	return title
+}
+
+// editTTL shows the remaining TTL of the key open in the editor and lets
+// the user extend it to a new value, refresh it or clear it. Changing the
+// TTL bumps the key's revision; the session follows it as long as the
+// editor was showing the version that was changed. changed receives the
+// new remaining TTL.
+func (c *Controller) editTTL(s *editSession, changed func(ttl int64)) {
+	pages := c.view.ActivePages()
+	cur, err := c.model.Get(c.ctx, s.node.Name)
+	if err != nil {
+		c.error("Cannot read key", err, false)
+		return
+	}
+	left, err := c.model.RemainingTTL(c.ctx, cur)
+	if err != nil {
+		c.error("Cannot read TTL", err, false)
+		return
+	}
+
+	form := c.view.NewTTLForm(cur.Name, left)
+	input := form.GetFormItem(1).(*tview.InputField)
+	apply := func(action string, op func(ttl int64) error) {
+		ttl, err := parseTTL(input.GetText())
+		if err != nil {
+			c.error("Invalid TTL", err, false)
+			return
+		}
+		pages.RemovePage("modal-ttl")
+		log.Debugf("TTL %s on %s (ttl %d)", action, cur.Name, ttl)
+		if err := op(ttl); err != nil {
+			c.error(fmt.Sprintf("Cannot %s TTL", action), err, false)
+			return
+		}
+		fresh, err := c.model.Get(c.ctx, cur.Name)
+		if err != nil {
+			c.error("Cannot read key", err, false)
+			return
+		}
+		if s.rev == cur.Revision() {
+			s.rev = fresh.Revision()
+		}
+		left, _ := c.model.RemainingTTL(c.ctx, fresh)
+		changed(left)
+	}
+
+	form.AddButton("Extend", func() {
+		apply("extend", func(ttl int64) error {
+			if ttl == 0 {
+				return fmt.Errorf("enter the new TTL in seconds, or use Clear")
+			}
+			return c.model.SetTTL(c.ctx, cur, ttl)
+		})
+	})
+	if left > 0 {
+		form.AddButton("Refresh", func() {
+			apply("refresh", func(ttl int64) error { return c.model.RefreshTTL(c.ctx, cur, ttl) })
+		})
+		form.AddButton("Clear", func() {
+			apply("clear", func(int64) error { return c.model.SetTTL(c.ctx, cur, 0) })
+		})
+	}
+	form.AddButton("Cancel", func() {
+		pages.RemovePage("modal-ttl")
+	})
+	form.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() == tcell.KeyEsc {
+			pages.RemovePage("modal-ttl")
+			return nil
+		}
+		return ev
+	})
+	pages.AddPage("modal-ttl", c.view.ModalEdit(form, 60, 9), true, true)
+}
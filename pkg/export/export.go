@@ -0,0 +1,90 @@
+// Package export renders the flat key/value map returned by
+// model.Export into the file formats offered by the export dialog.
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Options describes the export being written.
+type Options struct {
+	// Root is the exported directory. Nested and name-mangling formats
+	// are relative to it; flat JSON and scripts keep full keys.
+	Root string
+	// Protocol is the etcd API the keys came from ("v2" or "v3").
+	Protocol string
+}
+
+// Exporter writes exported keys in one file format.
+type Exporter interface {
+	// Name is shown in the format picker and used for lookups.
+	Name() string
+	// Ext is the default file extension, including the dot.
+	Ext() string
+	Write(w io.Writer, data map[string]string, opts Options) error
+}
+
+var registry []Exporter
+
+// Register adds an exporter; formats are listed in registration order.
+// Registering a name twice replaces the earlier exporter.
+func Register(e Exporter) {
+	for i, r := range registry {
+		if r.Name() == e.Name() {
+			registry[i] = e
+			return
+		}
+	}
+	registry = append(registry, e)
+}
+
+// Names returns the registered format names in registration order.
+func Names() []string {
+	names := make([]string, len(registry))
+	for i, e := range registry {
+		names[i] = e.Name()
+	}
+	return names
+}
+
+// Get returns the exporter registered under name.
+func Get(name string) (Exporter, error) {
+	for _, e := range registry {
+		if e.Name() == name {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown export format %q", name)
+}
+
+func init() {
+	Register(flatJSON{})
+	Register(treeJSON{})
+	Register(treeYAML{})
+	Register(dotenv{})
+	Register(properties{})
+	Register(etcdctlScript{})
+}
+
+// sortedKeys returns the keys of data in lexical order.
+func sortedKeys(data map[string]string) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// relSegments splits key into path segments relative to root.
+func relSegments(key, root string) []string {
+	root = "/" + strings.Trim(root, "/")
+	key = "/" + strings.Trim(key, "/")
+	if root != "/" {
+		key = strings.TrimPrefix(key, root+"/")
+	}
+	return strings.FieldsFunc(key, func(r rune) bool { return r == '/' })
+}
@@ -0,0 +1,311 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// flatJSON is the original {"/full/key": "value"} format; it is what
+// Import reads back.
+type flatJSON struct{}
+
+func (flatJSON) Name() string { return "json" }
+func (flatJSON) Ext() string  { return ".json" }
+
+func (flatJSON) Write(w io.Writer, data map[string]string, _ Options) error {
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(raw)
+	return err
+}
+
+// tree is a nested view of the keys below Options.Root: each path segment
+// becomes an object, values are string leaves.
+type tree map[string]any
+
+// leafKey holds the value of a key that is also a directory (v3 allows
+// both /a and /a/b to exist).
+const leafKey = "@value"
+
+func buildTree(data map[string]string, root string) tree {
+	t := tree{}
+	for _, k := range sortedKeys(data) {
+		segs := relSegments(k, root)
+		if len(segs) == 0 {
+			t[leafKey] = data[k]
+			continue
+		}
+		node := t
+		for _, s := range segs[:len(segs)-1] {
+			switch child := node[s].(type) {
+			case tree:
+				node = child
+			case string:
+				sub := tree{leafKey: child}
+				node[s] = sub
+				node = sub
+			default:
+				sub := tree{}
+				node[s] = sub
+				node = sub
+			}
+		}
+		last := segs[len(segs)-1]
+		if sub, ok := node[last].(tree); ok {
+			sub[leafKey] = data[k]
+		} else {
+			node[last] = data[k]
+		}
+	}
+	return t
+}
+
+type treeJSON struct{}
+
+func (treeJSON) Name() string { return "json-tree" }
+func (treeJSON) Ext() string  { return ".json" }
+
+func (treeJSON) Write(w io.Writer, data map[string]string, opts Options) error {
+	raw, err := json.MarshalIndent(buildTree(data, opts.Root), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(raw, '\n'))
+	return err
+}
+
+type treeYAML struct{}
+
+func (treeYAML) Name() string { return "yaml" }
+func (treeYAML) Ext() string  { return ".yaml" }
+
+func (treeYAML) Write(w io.Writer, data map[string]string, opts Options) error {
+	bw := bufio.NewWriter(w)
+	t := buildTree(data, opts.Root)
+	if len(t) == 0 {
+		bw.WriteString("{}\n")
+	} else {
+		writeYAML(bw, t, 0)
+	}
+	return bw.Flush()
+}
+
+func writeYAML(w *bufio.Writer, t tree, indent int) {
+	pad := strings.Repeat("  ", indent)
+	keys := make([]string, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch v := t[k].(type) {
+		case string:
+			fmt.Fprintf(w, "%s%s: %s\n", pad, yamlKey(k), yamlQuote(v))
+		case tree:
+			fmt.Fprintf(w, "%s%s:\n", pad, yamlKey(k))
+			writeYAML(w, v, indent+1)
+		}
+	}
+}
+
+var (
+	plainYAMLKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	// words a YAML 1.1 parser would read as booleans or null
+	reservedYAML = map[string]bool{
+		"true": true, "false": true, "yes": true, "no": true, "on": true,
+		"off": true, "y": true, "n": true, "null": true,
+	}
+)
+
+// yamlKey leaves simple identifiers bare and double-quotes everything else.
+// Values are always double-quoted.
+func yamlKey(k string) string {
+	if plainYAMLKey.MatchString(k) && !reservedYAML[strings.ToLower(k)] {
+		return k
+	}
+	return yamlQuote(k)
+}
+
+// yamlQuote writes s as a YAML double-quoted scalar. Characters YAML does
+// not allow as-is, and the ones it would fold as line breaks, are escaped
+// as \uXXXX. Invalid UTF-8 has no YAML form and becomes U+FFFD, as in the
+// JSON formats.
+func yamlQuote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range strings.ToValidUTF8(s, "\uFFFD") {
+		switch {
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '"':
+			sb.WriteString(`\"`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r < 0x20, r >= 0x7f && r <= 0x9f, r == 0x2028, r == 0x2029,
+			r == 0xfeff, r == 0xfffe, r == 0xffff:
+			fmt.Fprintf(&sb, `\u%04X`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+type dotenv struct{}
+
+func (dotenv) Name() string { return "dotenv" }
+func (dotenv) Ext() string  { return ".env" }
+
+// Write turns /app/db/host (relative to Root) into APP_DB_HOST="…". Keys
+// that end up with the same variable name, like /a/b-c and /a/b_c, fail
+// the export before anything is written.
+func (dotenv) Write(w io.Writer, data map[string]string, opts Options) error {
+	keys := sortedKeys(data)
+	names, err := uniqueNames(keys, func(k string) string {
+		return envName(relSegments(k, opts.Root))
+	})
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`", "\n", `\n`, "\r", `\r`)
+	for i, k := range keys {
+		if names[i] == "" {
+			continue
+		}
+		fmt.Fprintf(bw, "%s=\"%s\"\n", names[i], r.Replace(data[k]))
+	}
+	return bw.Flush()
+}
+
+// uniqueNames maps every key to its name, "" for keys that are skipped,
+// and fails if two keys share a name.
+func uniqueNames(keys []string, name func(string) string) ([]string, error) {
+	names := make([]string, len(keys))
+	seen := make(map[string]string, len(keys))
+	for i, k := range keys {
+		n := name(k)
+		if n == "" {
+			continue
+		}
+		if other, ok := seen[n]; ok {
+			return nil, fmt.Errorf("keys %s and %s would both be written as %s", other, k, n)
+		}
+		seen[n] = k
+		names[i] = n
+	}
+	return names, nil
+}
+
+func envName(segs []string) string {
+	var sb strings.Builder
+	for i, s := range segs {
+		if i > 0 {
+			sb.WriteByte('_')
+		}
+		for _, r := range strings.ToUpper(s) {
+			if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+				sb.WriteRune(r)
+			} else {
+				sb.WriteByte('_')
+			}
+		}
+	}
+	name := sb.String()
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+type properties struct{}
+
+func (properties) Name() string { return "properties" }
+func (properties) Ext() string  { return ".properties" }
+
+// Write turns /app/db/host (relative to Root) into app.db.host=…. Like
+// dotenv it refuses keys that share a name, e.g. /a/b.c and /a/b/c.
+func (properties) Write(w io.Writer, data map[string]string, opts Options) error {
+	keys := sortedKeys(data)
+	names, err := uniqueNames(keys, func(k string) string {
+		return strings.Join(relSegments(k, opts.Root), ".")
+	})
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for i, k := range keys {
+		if names[i] == "" {
+			continue
+		}
+		fmt.Fprintf(bw, "%s=%s\n", propEscape(names[i], true), propEscape(data[k], false))
+	}
+	return bw.Flush()
+}
+
+// propEscape applies java.util.Properties escaping; non-ASCII runes are
+// written as \uXXXX (surrogate pairs above the BMP).
+func propEscape(s string, key bool) string {
+	var sb strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r == ' ' && (key || i == 0):
+			sb.WriteString(`\ `)
+		case key && strings.ContainsRune("=:#!", r):
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			for _, u := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&sb, `\u%04x`, u)
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+type etcdctlScript struct{}
+
+func (etcdctlScript) Name() string { return "etcdctl" }
+func (etcdctlScript) Ext() string  { return ".sh" }
+
+// Write emits a shell script recreating the keys with `etcdctl put` (v3)
+// or `etcdctl set` (v2).
+func (etcdctlScript) Write(w io.Writer, data map[string]string, opts Options) error {
+	api, cmd := "3", "put"
+	if opts.Protocol == "v2" {
+		api, cmd = "2", "set"
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "#!/bin/sh\n# etcd-walker export of %s (%d keys)\nset -e\nexport ETCDCTL_API=%s\n\n", opts.Root, len(data), api)
+	for _, k := range sortedKeys(data) {
+		fmt.Fprintf(bw, "etcdctl %s -- %s %s\n", cmd, shQuote(k), shQuote(data[k]))
+	}
+	return bw.Flush()
+}
+
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,195 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// sample has keys and values with quotes, newlines, = and :, non-ASCII
+// text and a key that is also a directory.
+var sample = map[string]string{
+	"/app/db":      "also a dir",
+	"/app/db/host": "db.local",
+	"/app/db/pass": `p"a'ss=w:rd`,
+	"/app/größe":   "naïve ☃ 😀",
+	"/app/key=x:y": ` lead\slash`,
+	"/app/motd":    "line 1\nline 2",
+	"/app/true":    "$HOME",
+}
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", `{
+  "/app/db": "also a dir",
+  "/app/db/host": "db.local",
+  "/app/db/pass": "p\"a'ss=w:rd",
+  "/app/größe": "naïve ☃ 😀",
+  "/app/key=x:y": " lead\\slash",
+  "/app/motd": "line 1\nline 2",
+  "/app/true": "$HOME"
+}`},
+		{"json-tree", `{
+  "db": {
+    "@value": "also a dir",
+    "host": "db.local",
+    "pass": "p\"a'ss=w:rd"
+  },
+  "größe": "naïve ☃ 😀",
+  "key=x:y": " lead\\slash",
+  "motd": "line 1\nline 2",
+  "true": "$HOME"
+}
+`},
+		{"yaml", `db:
+  "@value": "also a dir"
+  host: "db.local"
+  pass: "p\"a'ss=w:rd"
+"größe": "naïve ☃ 😀"
+"key=x:y": " lead\\slash"
+motd: "line 1\nline 2"
+"true": "$HOME"
+`},
+		{"dotenv", `DB="also a dir"
+DB_HOST="db.local"
+DB_PASS="p\"a'ss=w:rd"
+GR__E="naïve ☃ 😀"
+KEY_X_Y=" lead\\slash"
+MOTD="line 1\nline 2"
+TRUE="\$HOME"
+`},
+		{"properties", `db=also a dir
+db.host=db.local
+db.pass=p"a'ss=w:rd
+gr\u00f6\u00dfe=na\u00efve \u2603 \ud83d\ude00
+key\=x\:y=\ lead\\slash
+motd=line 1\nline 2
+true=$HOME
+`},
+		{"etcdctl", `#!/bin/sh
+# etcd-walker export of /app (7 keys)
+set -e
+export ETCDCTL_API=3
+
+etcdctl put -- '/app/db' 'also a dir'
+etcdctl put -- '/app/db/host' 'db.local'
+etcdctl put -- '/app/db/pass' 'p"a'\''ss=w:rd'
+etcdctl put -- '/app/größe' 'naïve ☃ 😀'
+etcdctl put -- '/app/key=x:y' ' lead\slash'
+etcdctl put -- '/app/motd' 'line 1
+line 2'
+etcdctl put -- '/app/true' '$HOME'
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			e, err := Get(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := e.Write(&buf, sample, Options{Root: "/app", Protocol: "v3"}); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEtcdctlV2(t *testing.T) {
+	var buf bytes.Buffer
+	data := map[string]string{"/a": "it's"}
+	if err := (etcdctlScript{}).Write(&buf, data, Options{Root: "/", Protocol: "v2"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "#!/bin/sh\n# etcd-walker export of / (1 keys)\nset -e\nexport ETCDCTL_API=2\n\netcdctl set -- '/a' 'it'\\''s'\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNameCollisions(t *testing.T) {
+	tests := []struct {
+		format string
+		data   map[string]string
+	}{
+		{"dotenv", map[string]string{"/a/b-c": "1", "/a/b_c": "2"}},
+		{"dotenv", map[string]string{"/a/b/c": "1", "/a/b_c": "2"}},
+		{"properties", map[string]string{"/a/b.c": "1", "/a/b/c": "2"}},
+	}
+	for _, tt := range tests {
+		e, _ := Get(tt.format)
+		var buf bytes.Buffer
+		err := e.Write(&buf, tt.data, Options{Root: "/"})
+		if err == nil || !strings.Contains(err.Error(), "both") {
+			t.Errorf("%s %v: got error %v, want a collision", tt.format, tt.data, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%s %v: wrote %q before failing", tt.format, tt.data, buf.String())
+		}
+	}
+}
+
+func TestYAMLQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", `""`},
+		{"plain", `"plain"`},
+		{`a"b\c`, `"a\"b\\c"`},
+		{"tab\tcr\rlf\n", `"tab\tcr\rlf\n"`},
+		{"\x00\x1b\x7f", `"\u0000\u001B\u007F"`},
+		{"nel\u0085ls\u2028ps\u2029", `"nel\u0085ls\u2028ps\u2029"`},
+		{"\ufeffbom", `"\uFEFFbom"`},
+		{"ünï ☃ 😀", `"ünï ☃ 😀"`},
+		{"bad\xffbyte", "\"bad\uFFFDbyte\""},
+	}
+	for _, tt := range tests {
+		if got := yamlQuote(tt.in); got != tt.want {
+			t.Errorf("yamlQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		segs []string
+		want string
+	}{
+		{[]string{"app", "db", "host"}, "APP_DB_HOST"},
+		{[]string{"my-app", "db.host"}, "MY_APP_DB_HOST"},
+		{[]string{"1st"}, "_1ST"},
+		{[]string{"größe"}, "GR__E"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := envName(tt.segs); got != tt.want {
+			t.Errorf("envName(%q) = %q, want %q", tt.segs, got, tt.want)
+		}
+	}
+}
+
+func TestPropEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		key  bool
+		want string
+	}{
+		{"a=b:c#d!e", true, `a\=b\:c\#d\!e`},
+		{"a=b:c#d!e", false, "a=b:c#d!e"},
+		{"a b", true, `a\ b`},
+		{" a b", false, `\ a b`},
+		{"tab\tnl\ncr\r\\", false, `tab\tnl\ncr\r\\`},
+		{"é😀", false, `\u00e9\ud83d\ude00`},
+	}
+	for _, tt := range tests {
+		if got := propEscape(tt.in, tt.key); got != tt.want {
+			t.Errorf("propEscape(%q, %t) = %s, want %s", tt.in, tt.key, got, tt.want)
+		}
+	}
+}
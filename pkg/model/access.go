@@ -0,0 +1,297 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ErrPermissionDenied is returned when RBAC forbids reading or writing.
+var ErrPermissionDenied = errors.New("permission denied")
+
+func isPermissionDenied(err error) bool {
+	return err != nil && (errors.Is(err, rpctypes.ErrPermissionDenied) ||
+		strings.Contains(err.Error(), "permission denied"))
+}
+
+// AccessLevel is what the connected user may do with a node. The zero
+// value is full access, which is also what every node gets when the user
+// is not restricted (or its permissions could not be read).
+type AccessLevel int
+
+const (
+	AccessFull     AccessLevel = iota
+	AccessReadOnly             // readable, not writable
+	AccessPartial              // a directory that cannot be listed but leads to readable keys
+	AccessNone                 // not readable
+)
+
+func (a AccessLevel) String() string {
+	switch a {
+	case AccessReadOnly:
+		return "read-only"
+	case AccessPartial:
+		return "partially readable"
+	case AccessNone:
+		return "forbidden"
+	}
+	return "full"
+}
+
+// keyRange is a half-open key interval; an empty end means unbounded.
+type keyRange struct{ start, end string }
+
+func permRange(p Permission) keyRange {
+	switch p.RangeEnd {
+	case "":
+		return keyRange{p.Key, p.Key + "\x00"}
+	case "\x00":
+		return keyRange{p.Key, ""}
+	}
+	return keyRange{p.Key, p.RangeEnd}
+}
+
+// nodeRange is the keys a node stands for: the key itself, or everything
+// below a directory.
+func nodeRange(name string, isDir bool) keyRange {
+	if isDir {
+		pfx := withTrail(name)
+		return keyRange{pfx, clientv3.GetPrefixRangeEnd(pfx)}
+	}
+	k := normPath(name)
+	return keyRange{k, k + "\x00"}
+}
+
+func endBefore(a, b string) bool { return b == "" || (a != "" && a <= b) }
+
+// mergeRanges sorts ranges and joins overlapping or touching ones, so that
+// a range is covered by the union iff one merged range covers it.
+func mergeRanges(rs []keyRange) []keyRange {
+	sort.Slice(rs, func(i, j int) bool { return rs[i].start < rs[j].start })
+	var out []keyRange
+	for _, r := range rs {
+		if n := len(out); n > 0 && (out[n-1].end == "" || r.start <= out[n-1].end) {
+			if !endBefore(r.end, out[n-1].end) {
+				out[n-1].end = r.end
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func covers(rs []keyRange, r keyRange) bool {
+	for _, m := range rs {
+		if m.start <= r.start && endBefore(r.end, m.end) {
+			return true
+		}
+	}
+	return false
+}
+
+func intersects(rs []keyRange, r keyRange) bool {
+	for _, m := range rs {
+		if (r.end == "" || m.start < r.end) && (m.end == "" || r.start < m.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// access is the RBAC view of a non-root user, built from the permissions
+// of the roles it is allowed to read.
+type access struct {
+	perms []Permission
+	read  []keyRange
+	write []keyRange
+}
+
+// loadAccess reads the roles of user and their permissions. It returns nil
+// (unrestricted) for root or when the user's own record cannot be read.
+func (b *v3Backend) loadAccess(ctx context.Context, user string) *access {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+
+	u, err := b.c.UserGet(ctx, user)
+	if err != nil || slices.Contains(u.Roles, "root") {
+		return nil
+	}
+	a := &access{}
+	for _, role := range u.Roles {
+		r, err := b.c.RoleGet(ctx, role)
+		if err != nil {
+			// not every etcd lets a user read its own roles; skip those
+			continue
+		}
+		for _, p := range r.Perm {
+			perm := Permission{
+				Type:     permTypeName(clientv3.PermissionType(p.PermType)),
+				Key:      string(p.Key),
+				RangeEnd: string(p.RangeEnd),
+			}
+			a.perms = append(a.perms, perm)
+			if perm.Type != PermWrite {
+				a.read = append(a.read, permRange(perm))
+			}
+			if perm.Type != PermRead {
+				a.write = append(a.write, permRange(perm))
+			}
+		}
+	}
+	a.read, a.write = mergeRanges(a.read), mergeRanges(a.write)
+	return a
+}
+
+func (a *access) level(name string, isDir bool) AccessLevel {
+	r := nodeRange(name, isDir)
+	switch {
+	case covers(a.read, r) && covers(a.write, r):
+		return AccessFull
+	case covers(a.read, r):
+		return AccessReadOnly
+	case isDir && intersects(a.read, r):
+		return AccessPartial
+	}
+	return AccessNone
+}
+
+// startDir is the deepest directory containing every readable key.
+func (a *access) startDir() string {
+	var dirs [][]string
+	for _, p := range a.perms {
+		if p.Type == PermWrite {
+			continue
+		}
+		k := normPath(p.Key)
+		if p.Match() == MatchPrefix && strings.HasSuffix(p.Key, "/") {
+			k += "/x" // the prefix itself is a directory
+		}
+		dirs = append(dirs, strings.FieldsFunc(k, func(r rune) bool { return r == '/' }))
+	}
+	if len(dirs) == 0 {
+		return "/"
+	}
+	common := dirs[0][:max(len(dirs[0])-1, 0)]
+	for _, d := range dirs[1:] {
+		n := 0
+		for n < len(common) && n < len(d)-1 && common[n] == d[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	if len(common) == 0 {
+		return "/"
+	}
+	return "/" + strings.Join(common, "/") + "/"
+}
+
+// paths lists what can be seen of directory when it cannot be listed: a
+// directory for every permission leading below it and the keys granted
+// directly inside it.
+func (a *access) paths(ctx context.Context, b backend, directory string) []*Node {
+	prefix := withTrail(directory)
+	seen := map[string]bool{}
+	var nodes []*Node
+	for _, p := range a.perms {
+		k := normPath(p.Key)
+		if !strings.HasPrefix(k, prefix) || len(k) == len(prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(k, prefix)
+		child, _, below := strings.Cut(rest, "/")
+		isDir := below || p.Match() != MatchKey
+		full := strings.TrimSuffix(prefix, "/") + "/" + child
+		if seen[full] {
+			continue
+		}
+		seen[full] = true
+		if isDir {
+			nodes = append(nodes, &Node{Name: full, IsDir: true})
+			continue
+		}
+		if nd, err := b.get(ctx, full); err == nil && !nd.IsDir {
+			nodes = append(nodes, nd)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	return nodes
+}
+
+func (a *access) mark(nodes []*Node) {
+	for _, n := range nodes {
+		n.Access = a.level(n.Name, n.IsDir)
+	}
+}
+
+// Restricted reports whether the user is limited by RBAC permissions.
+func (m *Model) Restricted() bool { return m.access != nil }
+
+// StartDir is where browsing should begin: "/" unless the user is
+// restricted, then the deepest directory holding everything it may read.
+func (m *Model) StartDir() string {
+	if m.access == nil {
+		return "/"
+	}
+	return m.access.startDir()
+}
+
+// AccessOf returns what the user may do with a key or directory.
+func (m *Model) AccessOf(name string, isDir bool) AccessLevel {
+	if m.access == nil {
+		return AccessFull
+	}
+	return m.access.level(name, isDir)
+}
+
+// CanWriteUnder reports whether the user may write some key below
+// directory, i.e. whether offering to create one makes sense.
+func (m *Model) CanWriteUnder(directory string) bool {
+	return m.access == nil || intersects(m.access.write, nodeRange(directory, true))
+}
+
+// openV3 builds a model on b and probes it. Users restricted by RBAC get
+// their permissions loaded and are probed at StartDir, since listing "/"
+// is usually forbidden to them.
+func openV3(ctx context.Context, b *v3Backend, opts Options) (*Model, error) {
+	m := &Model{backend: b, readOnly: opts.ReadOnly}
+	if opts.Username != "" {
+		if en, known, _ := b.authStatus(ctx); known && en {
+			m.access = b.loadAccess(ctx, opts.Username)
+		}
+	}
+	if _, err := m.Ls(ctx, m.StartDir()); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Ls lists directory. For a restricted user a directory that cannot be
+// listed is shown as the paths the user's permissions lead through, and
+// every node carries its AccessLevel.
+func (m *Model) Ls(ctx context.Context, directory string) ([]*Node, error) {
+	nodes, err := m.backend.ls(ctx, directory)
+	if isPermissionDenied(err) {
+		if m.access != nil {
+			if nodes = m.access.paths(ctx, m.backend, directory); len(nodes) > 0 {
+				err = nil
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot list %s", ErrPermissionDenied, withTrail(directory))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.access != nil {
+		m.access.mark(nodes)
+	}
+	return nodes, nil
+}
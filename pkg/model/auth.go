@@ -0,0 +1,373 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// User is an etcd user and the roles granted to it (v3).
+type User struct {
+	Name  string
+	Roles []string
+}
+
+// Role is an etcd role and the key ranges it may access (v3).
+type Role struct {
+	Name  string
+	Perms []Permission
+}
+
+// Permission types.
+const (
+	PermRead      = "read"
+	PermWrite     = "write"
+	PermReadWrite = "readwrite"
+)
+
+// Ways a permission can select keys.
+const (
+	MatchKey     = "key"      // the single key Key
+	MatchPrefix  = "prefix"   // every key starting with Key
+	MatchRange   = "range"    // [Key, RangeEnd)
+	MatchFromKey = "from-key" // every key >= Key
+)
+
+// PermTypes and Matches list the values above in display order.
+var (
+	PermTypes = []string{PermRead, PermWrite, PermReadWrite}
+	Matches   = []string{MatchKey, MatchPrefix, MatchRange, MatchFromKey}
+)
+
+// Permission grants read and/or write access to a key range. RangeEnd is
+// empty for a single key, as in the etcd API.
+type Permission struct {
+	Type     string
+	Key      string
+	RangeEnd string
+}
+
+// NewPermission builds a permission from a type, a key and a match kind;
+// rangeEnd is only used with MatchRange.
+func NewPermission(typ, key, match, rangeEnd string) (Permission, error) {
+	if _, err := permType(typ); err != nil {
+		return Permission{}, err
+	}
+	if key == "" {
+		return Permission{}, fmt.Errorf("key must not be empty")
+	}
+	p := Permission{Type: typ, Key: key}
+	switch match {
+	case MatchKey:
+	case MatchPrefix:
+		p.RangeEnd = clientv3.GetPrefixRangeEnd(key)
+	case MatchFromKey:
+		p.RangeEnd = "\x00"
+	case MatchRange:
+		if rangeEnd == "" || rangeEnd <= key {
+			return Permission{}, fmt.Errorf("range end must sort after %q", key)
+		}
+		p.RangeEnd = rangeEnd
+	default:
+		return Permission{}, fmt.Errorf("unknown match %q", match)
+	}
+	return p, nil
+}
+
+// Match reports how p selects keys (one of the Match* constants).
+func (p Permission) Match() string {
+	switch p.RangeEnd {
+	case "":
+		return MatchKey
+	case "\x00":
+		return MatchFromKey
+	case clientv3.GetPrefixRangeEnd(p.Key):
+		return MatchPrefix
+	}
+	return MatchRange
+}
+
+func (p Permission) String() string {
+	switch p.Match() {
+	case MatchKey:
+		return fmt.Sprintf("%s %s", p.Type, p.Key)
+	case MatchPrefix:
+		return fmt.Sprintf("%s %s*", p.Type, p.Key)
+	case MatchFromKey:
+		return fmt.Sprintf("%s >= %s", p.Type, p.Key)
+	}
+	return fmt.Sprintf("%s [%s, %s)", p.Type, p.Key, p.RangeEnd)
+}
+
+func permType(typ string) (clientv3.PermissionType, error) {
+	switch typ {
+	case PermRead:
+		return clientv3.PermissionType(clientv3.PermRead), nil
+	case PermWrite:
+		return clientv3.PermissionType(clientv3.PermWrite), nil
+	case PermReadWrite:
+		return clientv3.PermissionType(clientv3.PermReadWrite), nil
+	}
+	return 0, fmt.Errorf("unknown permission type %q", typ)
+}
+
+var errAuthV2 = fmt.Errorf("%w: auth management requires etcd v3", ErrNotSupported)
+
+// AuthEnabled asks the server whether authentication is on.
+func (m *Model) AuthEnabled(ctx context.Context) (bool, error) {
+	en, known, err := m.backend.authStatus(ctx)
+	if !known {
+		if err == nil {
+			err = errAuthV2
+		}
+		return false, err
+	}
+	return en, nil
+}
+
+// EnableAuth turns authentication on. etcd refuses unless a root user with
+// the root role exists.
+func (m *Model) EnableAuth(ctx context.Context) error { return m.setAuth(ctx, true) }
+
+// DisableAuth turns authentication off.
+func (m *Model) DisableAuth(ctx context.Context) error { return m.setAuth(ctx, false) }
+
+func (m *Model) setAuth(ctx context.Context, enable bool) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	if err := m.backend.setAuth(ctx, enable); err != nil {
+		return err
+	}
+	m.authLabel = "OFF"
+	if enable {
+		m.authLabel = "ON"
+	}
+	return nil
+}
+
+// Users lists every user with its roles.
+func (m *Model) Users(ctx context.Context) ([]User, error) { return m.backend.users(ctx) }
+
+// Roles lists every role with its permissions.
+func (m *Model) Roles(ctx context.Context) ([]Role, error) { return m.backend.roles(ctx) }
+
+func (m *Model) AddUser(ctx context.Context, name, password string) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.addUser(ctx, name, password)
+}
+
+func (m *Model) DeleteUser(ctx context.Context, name string) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.deleteUser(ctx, name)
+}
+
+func (m *Model) ChangePassword(ctx context.Context, name, password string) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.changePassword(ctx, name, password)
+}
+
+func (m *Model) GrantRole(ctx context.Context, user, role string) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.grantRole(ctx, user, role)
+}
+
+func (m *Model) RevokeRole(ctx context.Context, user, role string) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.revokeRole(ctx, user, role)
+}
+
+func (m *Model) AddRole(ctx context.Context, name string) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.addRole(ctx, name)
+}
+
+func (m *Model) DeleteRole(ctx context.Context, name string) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.deleteRole(ctx, name)
+}
+
+// GrantPermission adds p to role; a permission on the same range is
+// replaced.
+func (m *Model) GrantPermission(ctx context.Context, role string, p Permission) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.grantPermission(ctx, role, p)
+}
+
+func (m *Model) RevokePermission(ctx context.Context, role string, p Permission) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.revokePermission(ctx, role, p)
+}
+
+func (b *v3Backend) setAuth(ctx context.Context, enable bool) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	var err error
+	if enable {
+		_, err = b.c.AuthEnable(ctx)
+	} else {
+		_, err = b.c.AuthDisable(ctx)
+	}
+	return err
+}
+
+func (b *v3Backend) users(ctx context.Context) ([]User, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.scan)
+	defer cancel()
+
+	resp, err := b.c.UserList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]User, 0, len(resp.Users))
+	for _, name := range resp.Users {
+		u, err := b.c.UserGet(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		roles := append([]string(nil), u.Roles...)
+		sort.Strings(roles)
+		out = append(out, User{Name: name, Roles: roles})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (b *v3Backend) roles(ctx context.Context) ([]Role, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.scan)
+	defer cancel()
+
+	resp, err := b.c.RoleList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Role, 0, len(resp.Roles))
+	for _, name := range resp.Roles {
+		r, err := b.c.RoleGet(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		role := Role{Name: name}
+		for _, p := range r.Perm {
+			role.Perms = append(role.Perms, Permission{
+				Type:     permTypeName(clientv3.PermissionType(p.PermType)),
+				Key:      string(p.Key),
+				RangeEnd: string(p.RangeEnd),
+			})
+		}
+		sort.Slice(role.Perms, func(i, j int) bool { return role.Perms[i].Key < role.Perms[j].Key })
+		out = append(out, role)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func permTypeName(t clientv3.PermissionType) string {
+	switch t {
+	case clientv3.PermissionType(clientv3.PermWrite):
+		return PermWrite
+	case clientv3.PermissionType(clientv3.PermReadWrite):
+		return PermReadWrite
+	}
+	return PermRead
+}
+
+func (b *v3Backend) addUser(ctx context.Context, name, password string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err := b.c.UserAdd(ctx, name, password)
+	return err
+}
+
+func (b *v3Backend) deleteUser(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err := b.c.UserDelete(ctx, name)
+	return err
+}
+
+func (b *v3Backend) changePassword(ctx context.Context, name, password string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err := b.c.UserChangePassword(ctx, name, password)
+	return err
+}
+
+func (b *v3Backend) grantRole(ctx context.Context, user, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err := b.c.UserGrantRole(ctx, user, role)
+	return err
+}
+
+func (b *v3Backend) revokeRole(ctx context.Context, user, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err := b.c.UserRevokeRole(ctx, user, role)
+	return err
+}
+
+func (b *v3Backend) addRole(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err := b.c.RoleAdd(ctx, name)
+	return err
+}
+
+func (b *v3Backend) deleteRole(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err := b.c.RoleDelete(ctx, name)
+	return err
+}
+
+func (b *v3Backend) grantPermission(ctx context.Context, role string, p Permission) error {
+	typ, err := permType(p.Type)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err = b.c.RoleGrantPermission(ctx, role, p.Key, p.RangeEnd, typ)
+	return err
+}
+
+func (b *v3Backend) revokePermission(ctx context.Context, role string, p Permission) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err := b.c.RoleRevokePermission(ctx, role, p.Key, p.RangeEnd)
+	return err
+}
+
+func (b *v2Backend) setAuth(context.Context, bool) error                        { return errAuthV2 }
+func (b *v2Backend) users(context.Context) ([]User, error)                      { return nil, errAuthV2 }
+func (b *v2Backend) roles(context.Context) ([]Role, error)                      { return nil, errAuthV2 }
+func (b *v2Backend) addUser(context.Context, string, string) error              { return errAuthV2 }
+func (b *v2Backend) deleteUser(context.Context, string) error                   { return errAuthV2 }
+func (b *v2Backend) changePassword(context.Context, string, string) error       { return errAuthV2 }
+func (b *v2Backend) grantRole(context.Context, string, string) error            { return errAuthV2 }
+func (b *v2Backend) revokeRole(context.Context, string, string) error           { return errAuthV2 }
+func (b *v2Backend) addRole(context.Context, string) error                      { return errAuthV2 }
+func (b *v2Backend) deleteRole(context.Context, string) error                   { return errAuthV2 }
+func (b *v2Backend) grantPermission(context.Context, string, Permission) error  { return errAuthV2 }
+func (b *v2Backend) revokePermission(context.Context, string, Permission) error { return errAuthV2 }
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	clientv2 "github.com/coreos/etcd/client"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ErrConflict is returned by SetIfUnchanged when the key was modified (or
+// deleted) after the expected revision was read.
+var ErrConflict = errors.New("key was modified by someone else")
+
+// Revision returns the node's modification revision: ModRevision on v3,
+// ModifiedIndex on v2. It is the value SetIfUnchanged expects.
+func (n *Node) Revision() int64 {
+	if n.ModRevision > 0 {
+		return n.ModRevision
+	}
+	return int64(n.ModifiedIndex)
+}
+
+// SetIfUnchanged writes value only if key is still at expectedModRev
+// (see Node.Revision). An expectedModRev of 0 means the key must not exist.
+func (m *Model) SetIfUnchanged(ctx context.Context, key, value string, expectedModRev int64) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.setIfUnchanged(ctx, key, value, expectedModRev)
+}
+
+func (b *v3Backend) setIfUnchanged(ctx context.Context, key, value string, expectedModRev int64) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+
+	k := normPath(key)
+	var putOpts []clientv3.OpOption
+	if expectedModRev != 0 {
+		// keep the key's lease; a plain put would detach it
+		putOpts = append(putOpts, clientv3.WithIgnoreLease())
+	}
+	resp, err := b.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(k), "=", expectedModRev)).
+		Then(clientv3.OpPut(k, value, putOpts...)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return fmt.Errorf("%w: %s", ErrConflict, k)
+	}
+	return nil
+}
+
+func (b *v2Backend) setIfUnchanged(ctx context.Context, key, value string, expectedModRev int64) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+
+	k := normPath(key)
+	opts := &clientv2.SetOptions{PrevIndex: uint64(expectedModRev)}
+	if expectedModRev == 0 {
+		opts = &clientv2.SetOptions{PrevExist: clientv2.PrevNoExist}
+	} else if cur, err := b.api.Get(ctx, k, nil); err == nil &&
+		cur.Node.ModifiedIndex == uint64(expectedModRev) && cur.Node.Expiration != nil {
+		// keep the key's TTL; a plain set would make it permanent
+		opts.TTL = time.Duration(v2TTLLeft(*cur.Node.Expiration)) * time.Second
+	}
+	_, err := b.api.Set(ctx, k, value, opts)
+	if err != nil {
+		var cerr clientv2.Error
+		if errors.As(err, &cerr) {
+			switch cerr.Code {
+			case clientv2.ErrorCodeTestFailed, clientv2.ErrorCodeKeyNotFound, clientv2.ErrorCodeNodeExist:
+				return fmt.Errorf("%w: %s", ErrConflict, k)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// v2TTLLeft rounds the time until exp up to whole seconds, at least 1,
+// the smallest TTL v2 accepts.
+func v2TTLLeft(exp time.Time) int64 {
+	return max(int64(math.Ceil(time.Until(exp).Seconds())), 1)
+}
@@ -0,0 +1,136 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	clientv2 "github.com/coreos/etcd/client"
+)
+
+// ClusterStatus is a snapshot of the cluster's membership and health.
+type ClusterStatus struct {
+	ClusterID string
+	Leader    string // member ID of the leader, "" if unknown
+	Members   []Member
+}
+
+// Member is one cluster member. IDs are hex strings on both protocols.
+type Member struct {
+	ID         string
+	Name       string
+	PeerURLs   []string
+	ClientURLs []string
+	IsLearner  bool
+
+	// Status is nil when the member could not be asked (see Err) or the
+	// protocol does not report it (v2).
+	Status *MemberStatus
+	Err    error
+}
+
+// MemberStatus is what a member reports about itself (v3 only).
+type MemberStatus struct {
+	Endpoint         string
+	Version          string
+	DBSize           int64
+	DBSizeInUse      int64
+	RaftTerm         uint64
+	RaftIndex        uint64
+	RaftAppliedIndex uint64
+	Errors           []string
+}
+
+// ClusterStatus lists the members and, on v3, asks each of them for its
+// status through its first client URL.
+func (m *Model) ClusterStatus(ctx context.Context) (*ClusterStatus, error) {
+	return m.backend.clusterStatus(ctx)
+}
+
+func (b *v3Backend) clusterStatus(ctx context.Context) (*ClusterStatus, error) {
+	lctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+
+	resp, err := b.c.MemberList(lctx)
+	if err != nil {
+		return nil, err
+	}
+	cs := &ClusterStatus{
+		ClusterID: fmt.Sprintf("%x", resp.Header.GetClusterId()),
+		Members:   make([]Member, len(resp.Members)),
+	}
+
+	var wg sync.WaitGroup
+	leaders := make([]uint64, len(resp.Members))
+	for i, pm := range resp.Members {
+		cs.Members[i] = Member{
+			ID:         fmt.Sprintf("%x", pm.ID),
+			Name:       pm.Name,
+			PeerURLs:   pm.PeerURLs,
+			ClientURLs: pm.ClientURLs,
+			IsLearner:  pm.IsLearner,
+		}
+		if len(pm.ClientURLs) == 0 {
+			cs.Members[i].Err = fmt.Errorf("member has not started yet")
+			continue
+		}
+
+		wg.Add(1)
+		go func(mb *Member, leader *uint64) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+			defer cancel()
+
+			ep := mb.ClientURLs[0]
+			st, err := b.c.Status(ctx, ep)
+			if err != nil {
+				mb.Err = err
+				return
+			}
+			*leader = st.Leader
+			mb.Status = &MemberStatus{
+				Endpoint:         ep,
+				Version:          st.Version,
+				DBSize:           st.DbSize,
+				DBSizeInUse:      st.DbSizeInUse,
+				RaftTerm:         st.RaftTerm,
+				RaftIndex:        st.RaftIndex,
+				RaftAppliedIndex: st.RaftAppliedIndex,
+				Errors:           st.Errors,
+			}
+		}(&cs.Members[i], &leaders[i])
+	}
+	wg.Wait()
+
+	for _, l := range leaders {
+		if l != 0 {
+			cs.Leader = fmt.Sprintf("%x", l)
+			break
+		}
+	}
+	return cs, nil
+}
+
+func (b *v2Backend) clusterStatus(ctx context.Context) (*ClusterStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+
+	api := clientv2.NewMembersAPI(b.client)
+	members, err := api.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cs := &ClusterStatus{Members: make([]Member, len(members))}
+	for i, pm := range members {
+		cs.Members[i] = Member{
+			ID:         pm.ID,
+			Name:       pm.Name,
+			PeerURLs:   pm.PeerURLs,
+			ClientURLs: pm.ClientURLs,
+		}
+	}
+	if leader, err := api.Leader(ctx); err == nil && leader != nil {
+		cs.Leader = leader.ID
+	}
+	return cs, nil
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	clientv2 "github.com/coreos/etcd/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// UseTLS reports whether connections are made over TLS: either TLSEnabled
+// is set or an endpoint is given as an https:// URL.
+func (o Options) UseTLS() bool {
+	if o.TLSEnabled {
+		return true
+	}
+	for _, ep := range o.Endpoints {
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(ep)), "https://") {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointURLs returns the endpoints to dial as scheme://host:port URLs.
+// Bare host:port entries get scheme; without Endpoints, Host and Port are
+// used. IPv6 literals may be given with or without brackets.
+func endpointURLs(opts Options, scheme string) ([]string, error) {
+	defPort := opts.Port
+	if defPort == "" {
+		defPort = "2379"
+	}
+
+	eps := opts.Endpoints
+	if len(eps) == 0 {
+		eps = []string{net.JoinHostPort(strings.Trim(opts.Host, "[]"), defPort)}
+	}
+
+	urls := make([]string, 0, len(eps))
+	for _, ep := range eps {
+		ep = strings.TrimSpace(ep)
+		if ep == "" {
+			continue
+		}
+		if !strings.Contains(ep, "://") {
+			// a bare IPv6 address has more than one colon and no brackets
+			if strings.Count(ep, ":") > 1 && !strings.HasPrefix(ep, "[") {
+				ep = "[" + ep + "]"
+			}
+			ep = scheme + "://" + ep
+		}
+		u, err := url.Parse(ep)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q: %w", ep, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("invalid endpoint %q: scheme must be http or https", ep)
+		}
+		if u.Hostname() == "" {
+			return nil, fmt.Errorf("invalid endpoint %q: missing host", ep)
+		}
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), defPort)
+		}
+		urls = append(urls, u.Scheme+"://"+u.Host)
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no etcd endpoints configured")
+	}
+	return urls, nil
+}
+
+// describeEndpoints formats the configured endpoints for error messages.
+func describeEndpoints(opts Options) string {
+	if len(opts.Endpoints) > 0 {
+		return strings.Join(opts.Endpoints, ",")
+	}
+	return net.JoinHostPort(strings.Trim(opts.Host, "[]"), opts.Port)
+}
+
+// Endpoint returns the endpoint that served the most recent request, or
+// the first configured one before any request succeeded.
+func (m *Model) Endpoint() string { return m.backend.endpoint() }
+
+// Endpoints returns every configured endpoint URL.
+func (m *Model) Endpoints() []string { return m.backend.endpoints() }
+
+// lastPeer remembers the host:port that answered the latest request.
+type lastPeer struct {
+	urls []string
+	addr atomic.Value // string
+}
+
+func (p *lastPeer) record(addr string) { p.addr.Store(addr) }
+
+// endpoint maps the last address back to a configured URL; v3 reports
+// resolved IPs, so an address matching no URL is returned as is.
+func (p *lastPeer) endpoint() string {
+	addr, _ := p.addr.Load().(string)
+	if addr == "" {
+		return p.urls[0]
+	}
+	for _, ep := range p.urls {
+		if u, err := url.Parse(ep); err == nil && u.Host == addr {
+			return ep
+		}
+	}
+	return addr
+}
+
+func (p *lastPeer) endpoints() []string { return p.urls }
+
+// unaryInterceptor records the peer of every successful v3 unary call.
+func (p *lastPeer) unaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var pr peer.Peer
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Peer(&pr))...)
+		if err == nil && pr.Addr != nil {
+			p.record(pr.Addr.String())
+		}
+		return err
+	}
+}
+
+// peerTransport records the endpoint of every successful v2 request.
+type peerTransport struct {
+	clientv2.CancelableTransport
+	peer *lastPeer
+}
+
+func (t peerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.CancelableTransport.RoundTrip(req)
+	if err == nil {
+		t.peer.record(req.URL.Host)
+	}
+	return resp, err
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// KeyVersion is one past (or the current) value of a key.
+type KeyVersion struct {
+	Value       string
+	ModRevision int64
+	Version     int64
+}
+
+// History returns up to limit versions of key, newest first. It walks back
+// through ModRevision-1 until the key's CreateRevision; truncated is set
+// when the walk stopped early at the compaction boundary or at limit.
+func (m *Model) History(ctx context.Context, key string, limit int) (versions []KeyVersion, truncated bool, err error) {
+	return m.backend.history(ctx, key, limit)
+}
+
+func (b *v3Backend) history(ctx context.Context, key string, limit int) ([]KeyVersion, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.scan)
+	defer cancel()
+
+	k := normPath(key)
+	resp, err := b.cli.Get(ctx, k, b.readOpts()...)
+	if err != nil {
+		return nil, false, v3revErr(err, b.pinned())
+	}
+	if resp.Count == 0 {
+		return nil, false, fmt.Errorf("key %w: %s", ErrNotFound, k)
+	}
+
+	kv := resp.Kvs[0]
+	created := kv.CreateRevision
+	versions := []KeyVersion{{Value: string(kv.Value), ModRevision: kv.ModRevision, Version: kv.Version}}
+
+	for rev := kv.ModRevision - 1; rev >= created; {
+		if limit > 0 && len(versions) >= limit {
+			return versions, true, nil
+		}
+		resp, err := b.cli.Get(ctx, k, clientv3.WithRev(rev))
+		if err != nil {
+			if errors.Is(v3revErr(err, rev), ErrCompacted) {
+				return versions, true, nil
+			}
+			return versions, false, err
+		}
+		if resp.Count == 0 || resp.Kvs[0].CreateRevision != created {
+			break
+		}
+		kv := resp.Kvs[0]
+		versions = append(versions, KeyVersion{Value: string(kv.Value), ModRevision: kv.ModRevision, Version: kv.Version})
+		rev = kv.ModRevision - 1
+	}
+	return versions, false, nil
+}
+
+func (b *v2Backend) history(ctx context.Context, key string, limit int) ([]KeyVersion, bool, error) {
+	return nil, false, fmt.Errorf("%w: key history requires etcd v3", ErrNotSupported)
+}
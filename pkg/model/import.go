@@ -0,0 +1,340 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	clientv2 "github.com/coreos/etcd/client"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ConflictPolicy decides what Import does with keys that already exist
+// with a different value.
+type ConflictPolicy int
+
+const (
+	ImportSkip ConflictPolicy = iota
+	ImportOverwrite
+	ImportFail
+)
+
+func (p ConflictPolicy) String() string {
+	switch p {
+	case ImportOverwrite:
+		return "overwrite"
+	case ImportFail:
+		return "fail"
+	}
+	return "skip"
+}
+
+// ErrImportConflict is returned by Import with ImportFail when some keys
+// already exist with a different value; nothing is written in that case.
+// It is also returned when keys changed between classification and the
+// write; those keys are left alone and listed in ImportReport.Conflicts.
+var ErrImportConflict = errors.New("import would change existing keys")
+
+// ImportOptions controls Import.
+type ImportOptions struct {
+	Policy ConflictPolicy
+
+	// Keys under FromPrefix are written under ToPrefix instead, e.g.
+	// /app/prod -> /app/staging, and a key equal to FromPrefix becomes
+	// ToPrefix. Empty FromPrefix imports keys as-is.
+	FromPrefix string
+	ToPrefix   string
+
+	// DryRun only classifies the keys; nothing is written.
+	DryRun bool
+}
+
+// ImportReport lists target keys by what Import did (or would do) to them.
+type ImportReport struct {
+	Create    []string
+	Update    []string
+	Unchanged []string
+	Skipped   []string // existing, different value, kept due to ImportSkip
+	Conflicts []string // changed by someone else before the write; not written
+}
+
+// importKV is a key Import writes: its new value and the revision it was
+// classified at (see Node.Revision), 0 if it must not exist yet.
+type importKV struct {
+	value string
+	rev   int64
+}
+
+// Import writes data (as produced by Export) into etcd. Existing keys are
+// read first and each key is classified as create, update, unchanged or
+// skipped before anything is written. Every write is guarded by the
+// revision its key was classified at. progress, if not nil, is called
+// with the keys written so far.
+func (m *Model) Import(ctx context.Context, data map[string]string, opts ImportOptions, progress func(done, total int)) (*ImportReport, error) {
+	if !opts.DryRun {
+		if err := m.writable(); err != nil {
+			return nil, err
+		}
+	}
+
+	target, err := rewriteKeys(data, opts.FromPrefix, opts.ToPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(target) == 0 {
+		return &ImportReport{}, nil
+	}
+
+	keys := make([]string, 0, len(target))
+	for k := range target {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	current := make(map[string]*Node)
+	err = m.backend.walk(ctx, commonDir(keys), false, func(nodes []*Node, _, _ int) {
+		for _, n := range nodes {
+			current[n.Name] = n
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &ImportReport{}
+	writes := make(map[string]importKV, len(target))
+	for _, k := range keys {
+		v := target[k]
+		old, exists := current[k]
+		switch {
+		case !exists:
+			rep.Create = append(rep.Create, k)
+			writes[k] = importKV{value: v}
+		case old.Value == v:
+			rep.Unchanged = append(rep.Unchanged, k)
+		case opts.Policy == ImportOverwrite:
+			rep.Update = append(rep.Update, k)
+			writes[k] = importKV{value: v, rev: old.Revision()}
+		default:
+			rep.Skipped = append(rep.Skipped, k)
+		}
+	}
+
+	if opts.Policy == ImportFail && len(rep.Skipped) > 0 {
+		return rep, fmt.Errorf("%w: %d key(s), e.g. %s", ErrImportConflict, len(rep.Skipped), rep.Skipped[0])
+	}
+	if opts.DryRun || len(writes) == 0 {
+		return rep, nil
+	}
+
+	conflicts, err := m.backend.putMany(ctx, writes, progress)
+	if len(conflicts) > 0 {
+		rep.Conflicts = conflicts
+		rep.Create = without(rep.Create, conflicts)
+		rep.Update = without(rep.Update, conflicts)
+	}
+	if err != nil {
+		return rep, err
+	}
+	if len(conflicts) > 0 {
+		return rep, fmt.Errorf("%w: %d key(s) changed during the import, e.g. %s", ErrImportConflict, len(conflicts), conflicts[0])
+	}
+	return rep, nil
+}
+
+// without returns keys minus the ones in drop.
+func without(keys, drop []string) []string {
+	skip := make(map[string]bool, len(drop))
+	for _, k := range drop {
+		skip[k] = true
+	}
+	out := keys[:0:0]
+	for _, k := range keys {
+		if !skip[k] {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// rewriteKeys normalises keys and moves those under from to to.
+func rewriteKeys(data map[string]string, from, to string) (map[string]string, error) {
+	out := make(map[string]string, len(data))
+	from = strings.TrimSpace(from)
+	for k, v := range data {
+		k = normPath(k)
+		if from != "" {
+			pfx := withTrail(from)
+			switch {
+			case k == normPath(from):
+				// the prefix itself is a key: it maps onto to
+				k = normPath(to)
+			case strings.HasPrefix(k, pfx):
+				k = normPath(withTrail(to) + strings.TrimPrefix(k, pfx))
+			default:
+				return nil, fmt.Errorf("key %s is outside of prefix %s", k, pfx)
+			}
+		}
+		if k == "/" || strings.HasSuffix(k, "/"+dirMarker) {
+			continue
+		}
+		out[k] = v
+	}
+	return out, nil
+}
+
+// commonDir returns the deepest directory containing all of keys.
+func commonDir(keys []string) string {
+	dir := parentDir(keys[0])
+	for _, k := range keys[1:] {
+		for dir != "/" && !strings.HasPrefix(k, withTrail(dir)) {
+			dir = parentDir(dir)
+		}
+	}
+	return dir
+}
+
+func parentDir(p string) string {
+	p = normPath(p)
+	i := strings.LastIndex(p, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return p[:i]
+}
+
+// CommonDir returns the deepest directory containing every key of data;
+// useful as the default FromPrefix of an import.
+func CommonDir(data map[string]string) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, normPath(k))
+	}
+	if len(keys) == 0 {
+		return "/"
+	}
+	return commonDir(keys)
+}
+
+// putMany writes kvs in transactions of up to maxTxnOps keys. Each key is
+// compared against the revision it was read at; a batch whose compare
+// fails is retried without the keys that changed, which are returned as
+// conflicts. ctx is only checked between batches.
+func (b *v3Backend) putMany(ctx context.Context, kvs map[string]importKV, progress func(done, total int)) ([]string, error) {
+	keys := sortedKeys(kvs)
+	var conflicts []string
+	report(progress, 0, len(keys))
+	for start := 0; start < len(keys); start += b.maxTxnOps {
+		if err := ctx.Err(); err != nil {
+			return conflicts, fmt.Errorf("after %d of %d keys: %w", start, len(keys), err)
+		}
+		end := start + b.maxTxnOps
+		if end > len(keys) {
+			end = len(keys)
+		}
+		for batch := keys[start:end]; len(batch) > 0; {
+			changed, err := b.putBatch(ctx, batch, kvs)
+			if err != nil {
+				return conflicts, fmt.Errorf("after %d of %d keys: %w", start, len(keys), err)
+			}
+			if len(changed) == 0 {
+				break
+			}
+			conflicts = append(conflicts, changed...)
+			batch = without(batch, changed)
+		}
+		report(progress, end, len(keys))
+	}
+	return conflicts, nil
+}
+
+// putBatch writes keys in one transaction if none of them changed since
+// it was read. Otherwise nothing is written and the changed keys are
+// returned.
+func (b *v3Backend) putBatch(ctx context.Context, keys []string, kvs map[string]importKV) ([]string, error) {
+	cmps := make([]clientv3.Cmp, 0, len(keys))
+	puts := make([]clientv3.Op, 0, len(keys))
+	gets := make([]clientv3.Op, 0, len(keys))
+	for _, k := range keys {
+		if kv := kvs[k]; kv.rev == 0 {
+			cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(k), "=", 0))
+		} else {
+			cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(k), "=", kv.rev))
+		}
+		puts = append(puts, clientv3.OpPut(k, kvs[k].value))
+		gets = append(gets, clientv3.OpGet(k, clientv3.WithKeysOnly()))
+	}
+
+	// a batch already sent is never abandoned: cancelling it would leave
+	// its outcome unknown
+	tctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.timeouts.bulk)
+	defer cancel()
+	resp, err := b.cli.Txn(tctx).If(cmps...).Then(puts...).Else(gets...).Commit()
+	if err != nil || resp.Succeeded {
+		return nil, err
+	}
+
+	var changed []string
+	for i, r := range resp.Responses {
+		var rev int64 // 0: the key does not exist
+		if got := r.GetResponseRange().GetKvs(); len(got) > 0 {
+			rev = got[0].ModRevision
+		}
+		if rev != kvs[keys[i]].rev {
+			changed = append(changed, keys[i])
+		}
+	}
+	if len(changed) == 0 {
+		// the reads run at the revision the compares saw; not expected
+		return nil, fmt.Errorf("%w: transaction refused", ErrConflict)
+	}
+	return changed, nil
+}
+
+// putMany writes kvs one key at a time, each guarded by the index it was
+// read at and with a request timeout of its own; keys that changed
+// meanwhile are returned as conflicts. ctx is only checked between keys.
+func (b *v2Backend) putMany(ctx context.Context, kvs map[string]importKV, progress func(done, total int)) ([]string, error) {
+	keys := sortedKeys(kvs)
+	var conflicts []string
+	report(progress, 0, len(keys))
+	for i, k := range keys {
+		if err := ctx.Err(); err != nil {
+			return conflicts, fmt.Errorf("after %d of %d keys: %w", i, len(keys), err)
+		}
+		opts := &clientv2.SetOptions{PrevIndex: uint64(kvs[k].rev)}
+		if kvs[k].rev == 0 {
+			opts = &clientv2.SetOptions{PrevExist: clientv2.PrevNoExist}
+		}
+		// a write already sent is never abandoned: cancelling it would
+		// leave its outcome unknown
+		sctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.timeouts.request)
+		_, err := b.api.Set(sctx, k, kvs[k].value, opts)
+		cancel()
+		if err != nil {
+			var cerr clientv2.Error
+			if !errors.As(err, &cerr) {
+				return conflicts, fmt.Errorf("after %d of %d keys: %w", i, len(keys), err)
+			}
+			switch cerr.Code {
+			case clientv2.ErrorCodeTestFailed, clientv2.ErrorCodeKeyNotFound, clientv2.ErrorCodeNodeExist:
+				conflicts = append(conflicts, k)
+			default:
+				return conflicts, fmt.Errorf("after %d of %d keys: %w", i, len(keys), err)
+			}
+		}
+		report(progress, i+1, len(keys))
+	}
+	return conflicts, nil
+}
+
+func sortedKeys(kvs map[string]importKV) []string {
+	keys := make([]string, 0, len(kvs))
+	for k := range kvs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
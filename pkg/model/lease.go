@@ -0,0 +1,171 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Lease is a v3 lease with the keys attached to it.
+type Lease struct {
+	ID         int64
+	TTL        int64 // seconds left when read; -1 once expired
+	GrantedTTL int64
+	Keys       []string
+}
+
+// Leases lists every lease with its remaining TTL and attached keys (v3).
+func (m *Model) Leases(ctx context.Context) ([]Lease, error) { return m.backend.leases(ctx) }
+
+// Lease reads one lease (v3).
+func (m *Model) Lease(ctx context.Context, id int64) (*Lease, error) { return m.backend.lease(ctx, id) }
+
+// GrantLease creates a lease of ttl seconds and returns its ID (v3).
+func (m *Model) GrantLease(ctx context.Context, ttl int64) (int64, error) {
+	if err := m.writable(); err != nil {
+		return 0, err
+	}
+	return m.backend.grantLease(ctx, ttl)
+}
+
+// RevokeLease revokes a lease, deleting every key attached to it (v3).
+func (m *Model) RevokeLease(ctx context.Context, id int64) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.revokeLease(ctx, id)
+}
+
+// AttachLease moves an existing key to lease id, or detaches it from any
+// lease when id is 0. The value is kept; the write only happens if the key
+// is still at expectedModRev (see Node.Revision).
+func (m *Model) AttachLease(ctx context.Context, key string, id int64, expectedModRev int64) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.attachLease(ctx, key, id, expectedModRev)
+}
+
+// SetWithLease writes value under lease id, or without a lease when id is
+// 0, if key is still at expectedModRev (see SetIfUnchanged). A lease the
+// key leaves is not revoked; other clients may still use it, and an
+// unused one expires on its own (v3).
+func (m *Model) SetWithLease(ctx context.Context, key, value string, id int64, expectedModRev int64) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.setWithLease(ctx, key, value, id, expectedModRev)
+}
+
+func (b *v3Backend) leases(ctx context.Context) ([]Lease, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.scan)
+	defer cancel()
+
+	resp, err := b.c.Leases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Lease, 0, len(resp.Leases))
+	for _, ls := range resp.Leases {
+		l, err := b.timeToLive(ctx, int64(ls.ID))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *l)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (b *v3Backend) lease(ctx context.Context, id int64) (*Lease, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	return b.timeToLive(ctx, id)
+}
+
+func (b *v3Backend) timeToLive(ctx context.Context, id int64) (*Lease, error) {
+	ttl, err := b.c.TimeToLive(ctx, clientv3.LeaseID(id), clientv3.WithAttachedKeys())
+	if err != nil {
+		return nil, err
+	}
+	l := &Lease{ID: id, TTL: ttl.TTL, GrantedTTL: ttl.GrantedTTL}
+	for _, k := range ttl.Keys {
+		l.Keys = append(l.Keys, string(k))
+	}
+	sort.Strings(l.Keys)
+	return l, nil
+}
+
+func (b *v3Backend) grantLease(ctx context.Context, ttl int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	resp, err := b.c.Grant(ctx, ttl)
+	if err != nil {
+		return 0, err
+	}
+	return int64(resp.ID), nil
+}
+
+func (b *v3Backend) revokeLease(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err := b.c.Revoke(ctx, clientv3.LeaseID(id))
+	return err
+}
+
+func (b *v3Backend) attachLease(ctx context.Context, key string, id int64, expectedModRev int64) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+
+	k := normPath(key)
+	opts := []clientv3.OpOption{clientv3.WithIgnoreValue()}
+	if id != 0 {
+		opts = append(opts, clientv3.WithLease(clientv3.LeaseID(id)))
+	}
+	resp, err := b.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(k), "=", expectedModRev)).
+		Then(clientv3.OpPut(k, "", opts...)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return fmt.Errorf("%w: %s", ErrConflict, k)
+	}
+	return nil
+}
+
+func (b *v3Backend) setWithLease(ctx context.Context, key, value string, id int64, expectedModRev int64) error {
+	k := normPath(key)
+	var opts []clientv3.OpOption
+	if id != 0 {
+		opts = append(opts, clientv3.WithLease(clientv3.LeaseID(id)))
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	resp, err := b.cli.Txn(tctx).
+		If(clientv3.Compare(clientv3.ModRevision(k), "=", expectedModRev)).
+		Then(clientv3.OpPut(k, value, opts...)).
+		Commit()
+	cancel()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return fmt.Errorf("%w: %s", ErrConflict, k)
+	}
+	return nil
+}
+
+var errLeasesV2 = fmt.Errorf("%w: leases require etcd v3", ErrNotSupported)
+
+func (b *v2Backend) leases(context.Context) ([]Lease, error)                 { return nil, errLeasesV2 }
+func (b *v2Backend) lease(context.Context, int64) (*Lease, error)            { return nil, errLeasesV2 }
+func (b *v2Backend) grantLease(context.Context, int64) (int64, error)        { return 0, errLeasesV2 }
+func (b *v2Backend) revokeLease(context.Context, int64) error                { return errLeasesV2 }
+func (b *v2Backend) attachLease(context.Context, string, int64, int64) error { return errLeasesV2 }
+func (b *v2Backend) setWithLease(context.Context, string, string, int64, int64) error {
+	return errLeasesV2
+}
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Alarm is an alarm raised by a member, e.g. NOSPACE when its database
+// hit the quota (v3).
+type Alarm struct {
+	MemberID string // hex, as in ClusterStatus
+	Type     string // NOSPACE, CORRUPT
+}
+
+// MemberHash is one member's hash of the keyspace (v3). Members that agree
+// on Revision and CompactRevision must report the same Hash.
+type MemberHash struct {
+	MemberID        string
+	Name            string
+	Endpoint        string
+	Revision        int64
+	CompactRevision int64
+	Hash            uint32
+	Err             error
+}
+
+var errMaintenanceV2 = fmt.Errorf("%w: maintenance requires etcd v3", ErrNotSupported)
+
+// Compact discards every revision older than rev cluster-wide. It returns
+// once the members have applied it, so DB sizes read afterwards show the
+// space freed inside the file; Defragment gives it back to the OS.
+func (m *Model) Compact(ctx context.Context, rev int64) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.compact(ctx, rev)
+}
+
+// Defragment rewrites the database of the member serving endpoint. The
+// member does not serve requests while it runs.
+func (m *Model) Defragment(ctx context.Context, endpoint string) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.defragment(ctx, endpoint)
+}
+
+// HashKV asks every member for its keyspace hash at rev (0 = the current
+// revision), to tell whether their data has diverged.
+func (m *Model) HashKV(ctx context.Context, rev int64) ([]MemberHash, error) {
+	return m.backend.hashKV(ctx, rev)
+}
+
+// Alarms lists the alarms raised in the cluster.
+func (m *Model) Alarms(ctx context.Context) ([]Alarm, error) { return m.backend.alarms(ctx) }
+
+// DisarmAlarms clears every alarm. A NOSPACE alarm comes straight back
+// unless space was freed first (compact, then defragment).
+func (m *Model) DisarmAlarms(ctx context.Context) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.disarmAlarms(ctx)
+}
+
+func (b *v3Backend) compact(ctx context.Context, rev int64) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.maintenance)
+	defer cancel()
+	_, err := b.c.Compact(ctx, rev, clientv3.WithCompactPhysical())
+	return v3revErr(err, rev)
+}
+
+func (b *v3Backend) defragment(ctx context.Context, endpoint string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.maintenance)
+	defer cancel()
+	_, err := b.c.Defragment(ctx, endpoint)
+	return err
+}
+
+func (b *v3Backend) hashKV(ctx context.Context, rev int64) ([]MemberHash, error) {
+	if rev == 0 {
+		// pin one revision so members that are merely behind by a few
+		// writes are not reported as diverged
+		head, err := b.headRevision(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rev = head
+	}
+	lctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	resp, err := b.c.MemberList(lctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]MemberHash, len(resp.Members))
+	var wg sync.WaitGroup
+	for i, pm := range resp.Members {
+		out[i] = MemberHash{MemberID: fmt.Sprintf("%x", pm.ID), Name: pm.Name, Revision: rev}
+		if len(pm.ClientURLs) == 0 {
+			out[i].Err = fmt.Errorf("member has not started yet")
+			continue
+		}
+		out[i].Endpoint = pm.ClientURLs[0]
+		wg.Add(1)
+		go func(mh *MemberHash) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, b.timeouts.scan)
+			defer cancel()
+			hr, err := b.c.HashKV(ctx, mh.Endpoint, mh.Revision)
+			if err != nil {
+				mh.Err = v3revErr(err, mh.Revision)
+				return
+			}
+			mh.Hash, mh.CompactRevision = hr.Hash, hr.CompactRevision
+		}(&out[i])
+	}
+	wg.Wait()
+	return out, nil
+}
+
+func (b *v3Backend) alarms(ctx context.Context) ([]Alarm, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	resp, err := b.c.AlarmList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Alarm, 0, len(resp.Alarms))
+	for _, a := range resp.Alarms {
+		out = append(out, Alarm{MemberID: fmt.Sprintf("%x", a.MemberID), Type: a.Alarm.String()})
+	}
+	return out, nil
+}
+
+func (b *v3Backend) disarmAlarms(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	// a zero AlarmMember disarms every raised alarm
+	_, err := b.c.AlarmDisarm(ctx, &clientv3.AlarmMember{})
+	return err
+}
+
+func (b *v2Backend) compact(context.Context, int64) error     { return errMaintenanceV2 }
+func (b *v2Backend) defragment(context.Context, string) error { return errMaintenanceV2 }
+func (b *v2Backend) hashKV(context.Context, int64) ([]MemberHash, error) {
+	return nil, errMaintenanceV2
+}
+func (b *v2Backend) alarms(context.Context) ([]Alarm, error) { return nil, errMaintenanceV2 }
+func (b *v2Backend) disarmAlarms(context.Context) error      { return errMaintenanceV2 }
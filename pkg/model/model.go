@@ -4,13 +4,17 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 
 	// v3 client
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -18,9 +22,14 @@ import (
 	clientv2 "github.com/coreos/etcd/client"
 )
 
+// ErrNotFound is returned when a key or directory does not exist.
+var ErrNotFound = errors.New("not found")
+
 type Model struct {
 	backend   backend
 	authLabel string
+	readOnly  bool
+	access    *access // nil unless the user is restricted by RBAC
 }
 
 type Node struct {
@@ -28,6 +37,25 @@ type Node struct {
 	ClusterId string
 	IsDir     bool
 	Value     string
+
+	// v3 MVCC metadata (zero on v2 and for synthetic v3 directories)
+	CreateRevision int64
+	ModRevision    int64
+	Version        int64
+	Lease          int64
+
+	// v2 metadata (zero on v3)
+	CreatedIndex  uint64
+	ModifiedIndex uint64
+	Expiration    *time.Time
+	TTL           int64
+
+	// Access is set by Ls for users restricted by RBAC (v3)
+	Access AccessLevel
+
+	// Lazy marks a key listed without its value (v3 Ls reads keys only);
+	// Model.Load fetches it
+	Lazy bool
 }
 
 type Options struct {
@@ -37,6 +65,10 @@ type Options struct {
 	Username string
 	Password string
 
+	// Endpoints lists cluster members as URLs or host:port; when set it
+	// replaces Host and Port and the clients fail over between them.
+	Endpoints []string
+
 	// TLS (v3 only)
 	TLSEnabled    bool
 	TLSCAFile     string
@@ -44,11 +76,35 @@ type Options struct {
 	TLSKeyFile    string
 	TLSSkipVerify bool
 
-	// TimeoutSeconds for etcd operations; 0 defaults to 5s
+	// TimeoutSeconds for a single etcd request; 0 defaults to 5s
 	TimeoutSeconds int
+	// ScanTimeoutSeconds for requests reading or deleting a whole subtree
+	// (export, history, leases, hashes); 0 defaults to 10x TimeoutSeconds,
+	// at least 30s
+	ScanTimeoutSeconds int
+	// BulkTimeoutSeconds for imports and for each batch of a move or a
+	// subtree delete; 0 defaults to 4x TimeoutSeconds, at least 20s
+	BulkTimeoutSeconds int
+	// MaintenanceTimeoutSeconds for a compaction or defragmentation; 0
+	// defaults to 5m
+	MaintenanceTimeoutSeconds int
+
+	// MaxTxnOps is the server's --max-txn-ops; 0 defaults to 128 (v3 only)
+	MaxTxnOps int
+
+	// ReadOnly makes every write fail with ErrReadOnly
+	ReadOnly bool
+
+	// Snapshot is an etcd v3 database file (a saved snapshot or a member's
+	// member/snap/db) to browse read-only instead of connecting
+	Snapshot string
 }
 
 func (m *Model) ProtocolVersion() string { return m.backend.proto() }
+
+// Close releases the connection; the model must not be used afterwards.
+func (m *Model) Close() error { return m.backend.close() }
+
 func (m *Model) AuthLabel() string {
 	if m == nil || m.authLabel == "" {
 		return "?"
@@ -56,36 +112,150 @@ func (m *Model) AuthLabel() string {
 	return m.authLabel
 }
 
-func (m *Model) Ls(directory string) ([]*Node, error)  { return m.backend.ls(directory) }
-func (m *Model) Get(key string) (*Node, error)         { return m.backend.get(key) }
-func (m *Model) Set(key, value string) error           { return m.backend.set(key, value) }
-func (m *Model) MkDir(directory string) error          { return m.backend.mkdir(directory) }
-func (m *Model) Del(key string) error                  { return m.backend.del(key) }
-func (m *Model) DelDir(key string) error               { return m.backend.deldir(key) }
-func (m *Model) RenameDir(oldDir, newDir string) error { return m.backend.renameDir(oldDir, newDir) }
-func (m *Model) RenameKey(oldKey, newKey string) error { return m.backend.renameKey(oldKey, newKey) }
-func (m *Model) Export(dir string) (map[string]string, error) { return m.backend.export(dir) }
+func (m *Model) Get(ctx context.Context, key string) (*Node, error) { return m.backend.get(ctx, key) }
 
-type backend interface {
-	proto() string
-	ls(directory string) ([]*Node, error)
-	get(key string) (*Node, error)
-	set(key, value string) error
-	mkdir(directory string) error
-	del(key string) error
-	deldir(key string) error
-	renameDir(oldDir, newDir string) error
-	renameKey(oldKey, newKey string) error
-	authStatus() (enabled bool, known bool, err error)
-	export(dir string) (map[string]string, error)
+// Export reads every key under dir. progress, if not nil, is called with
+// the keys read so far and the total as the read goes on.
+func (m *Model) Export(ctx context.Context, dir string, progress func(done, total int)) (map[string]string, error) {
+	return m.backend.export(ctx, dir, progress)
+}
+
+// Load returns n with its value, fetching the key if n was listed without
+// one; other nodes are returned as they are.
+func (m *Model) Load(ctx context.Context, n *Node) (*Node, error) {
+	if !n.Lazy {
+		return n, nil
+	}
+	full, err := m.backend.get(ctx, n.Name)
+	if err != nil {
+		return nil, err
+	}
+	if full.IsDir {
+		return nil, fmt.Errorf("%w: %s is no longer a key", ErrNotFound, n.Name)
+	}
+	full.Access = n.Access
+	return full, nil
+}
+
+func (m *Model) Set(ctx context.Context, key, value string) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.set(ctx, key, value)
+}
+
+func (m *Model) MkDir(ctx context.Context, directory string) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.mkdir(ctx, directory)
+}
+
+func (m *Model) Del(ctx context.Context, key string) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.del(ctx, key)
+}
+
+// DeleteError reports a directory delete that stopped part-way. Deleted
+// lists the keys already gone; the others are untouched.
+type DeleteError struct {
+	Deleted []string
+	Total   int
+	Err     error
 }
 
-func NewModel(opts Options) (*Model, error) {
-	host, port := opts.Host, opts.Port
+func (e *DeleteError) Error() string {
+	return fmt.Sprintf("deleted %d of %d keys before stopping: %v", len(e.Deleted), e.Total, e.Err)
+}
+
+func (e *DeleteError) Unwrap() error { return e.Err }
+
+// DelDir deletes a directory and everything below it. On v3 a subtree too
+// large for one transaction is deleted in batches; a failure or a
+// cancelled ctx stops between batches and is returned as *DeleteError.
+// progress, if not nil, is called with the keys deleted so far.
+func (m *Model) DelDir(ctx context.Context, key string, progress func(done, total int)) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.deldir(ctx, key, progress)
+}
+
+// report calls progress unless it is nil.
+func report(progress func(done, total int), done, total int) {
+	if progress != nil {
+		progress(done, total)
+	}
+}
 
+type backend interface {
+	proto() string
+	ls(ctx context.Context, directory string) ([]*Node, error)
+	get(ctx context.Context, key string) (*Node, error)
+	set(ctx context.Context, key, value string) error
+	mkdir(ctx context.Context, directory string) error
+	del(ctx context.Context, key string) error
+	deldir(ctx context.Context, key string, progress func(done, total int)) error
+	planMove(ctx context.Context, from, to string, isDir bool) (*MovePlan, error)
+	move(ctx context.Context, p *MovePlan, overwrite bool, progress func(done, total int)) error
+	putMany(ctx context.Context, kvs map[string]importKV, progress func(done, total int)) ([]string, error)
+	authStatus(ctx context.Context) (enabled bool, known bool, err error)
+	export(ctx context.Context, dir string, progress func(done, total int)) (map[string]string, error)
+	walk(ctx context.Context, dir string, keysOnly bool, visit func(nodes []*Node, done, total int)) error
+	watch(ctx context.Context, directory string) (<-chan []WatchEvent, func())
+	pin(ctx context.Context, rev int64) error
+	pinned() int64
+	headRevision(ctx context.Context) (int64, error)
+	history(ctx context.Context, key string, limit int) ([]KeyVersion, bool, error)
+	setIfUnchanged(ctx context.Context, key, value string, expectedModRev int64) error
+	endpoint() string
+	endpoints() []string
+	close() error
+	clusterStatus(ctx context.Context) (*ClusterStatus, error)
+	leases(ctx context.Context) ([]Lease, error)
+	lease(ctx context.Context, id int64) (*Lease, error)
+	grantLease(ctx context.Context, ttl int64) (int64, error)
+	revokeLease(ctx context.Context, id int64) error
+	attachLease(ctx context.Context, key string, id int64, expectedModRev int64) error
+	setWithLease(ctx context.Context, key, value string, id int64, expectedModRev int64) error
+	setWithTTL(ctx context.Context, key, value string, ttl int64) error
+	mkdirWithTTL(ctx context.Context, directory string, ttl int64) error
+	remainingTTL(ctx context.Context, n *Node) (int64, error)
+	setTTL(ctx context.Context, n *Node, ttl int64) error
+	refreshTTL(ctx context.Context, n *Node, ttl int64) error
+	setAuth(ctx context.Context, enable bool) error
+	users(ctx context.Context) ([]User, error)
+	roles(ctx context.Context) ([]Role, error)
+	addUser(ctx context.Context, name, password string) error
+	deleteUser(ctx context.Context, name string) error
+	changePassword(ctx context.Context, name, password string) error
+	grantRole(ctx context.Context, user, role string) error
+	revokeRole(ctx context.Context, user, role string) error
+	addRole(ctx context.Context, name string) error
+	deleteRole(ctx context.Context, name string) error
+	grantPermission(ctx context.Context, role string, p Permission) error
+	revokePermission(ctx context.Context, role string, p Permission) error
+	snapshot(ctx context.Context, w io.Writer, progress func(done, total int64)) (int64, error)
+	compact(ctx context.Context, rev int64) error
+	defragment(ctx context.Context, endpoint string) error
+	hashKV(ctx context.Context, rev int64) ([]MemberHash, error)
+	alarms(ctx context.Context) ([]Alarm, error)
+	disarmAlarms(ctx context.Context) error
+}
+
+func NewModel(ctx context.Context, opts Options) (*Model, error) {
 	if strings.TrimSpace(opts.Username) == "" && strings.TrimSpace(opts.Password) != "" {
 		return nil, fmt.Errorf("auth misconfigured: password is set but username is empty (set --username or username in config)")
 	}
+	if opts.Snapshot != "" {
+		b, err := openSnapshot(opts.Snapshot)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot: %w", err)
+		}
+		return &Model{backend: b, readOnly: true, authLabel: "-"}, nil
+	}
 	switch strings.ToLower(strings.TrimSpace(opts.Protocol)) {
 
 	case "v3":
@@ -93,12 +263,13 @@ func NewModel(opts Options) (*Model, error) {
 		if err != nil {
 			return nil, fmt.Errorf("v3 init failed: %w", err)
 		}
-		if _, err := b3.ls("/"); err != nil {
+		m, err := openV3(ctx, b3, opts)
+		if err != nil {
 			return nil, fmt.Errorf("v3 probe failed: %w", err)
 		}
 
 		label := "?"
-		if en, known, _ := b3.authStatus(); known {
+		if en, known, _ := b3.authStatus(ctx); known {
 			if en {
 				label = "ON"
 			} else {
@@ -106,42 +277,46 @@ func NewModel(opts Options) (*Model, error) {
 			}
 		}
 
-		return &Model{backend: b3, authLabel: label}, nil
+		m.authLabel = label
+		return m, nil
 
 	case "auto":
 		if b3, err := newV3Backend(opts); err == nil {
-			if _, err := b3.ls("/"); err == nil {
-				return &Model{backend: b3}, nil
+			if m, err := openV3(ctx, b3, opts); err == nil {
+				return m, nil
 			} else if isAuthRequiredErr(err) {
 				return nil, fmt.Errorf("etcd auth is enabled; provide --username/--password (or set them in config). Original: %w", err)
 			}
 		}
 		if b2, err := newV2Backend(opts); err == nil {
-			if _, err := b2.ls("/"); err == nil {
-				return &Model{backend: b2}, nil
+			if _, err := b2.ls(ctx, "/"); err == nil {
+				return &Model{backend: b2, readOnly: opts.ReadOnly}, nil
 			} else if isAuthRequiredErr(err) {
 				return nil, fmt.Errorf("etcd auth is enabled; provide --username/--password (or set them in config). Original: %w", err)
 			}
 		}
 
-		return nil, fmt.Errorf("auto: neither v3 nor v2 reachable at %s:%s", host, port)
+		return nil, fmt.Errorf("auto: neither v3 nor v2 reachable at %s", describeEndpoints(opts))
 
 	default: // v2
 		b2, err := newV2Backend(opts)
 		if err != nil {
 			return nil, fmt.Errorf("v2 init failed: %w", err)
 		}
-		if _, err := b2.ls("/"); err != nil {
+		if _, err := b2.ls(ctx, "/"); err != nil {
 			return nil, fmt.Errorf("v2 probe failed: %w", err)
 		}
-		return &Model{backend: b2}, nil
+		return &Model{backend: b2, readOnly: opts.ReadOnly}, nil
 	}
 }
 
 type v3Backend struct {
-	cli     clientv3.KV
-	c       *clientv3.Client
-	timeout time.Duration
+	cli       clientv3.KV
+	c         *clientv3.Client
+	timeouts  timeouts
+	maxTxnOps int
+	revPin
+	*lastPeer
 }
 
 func isAuthRequiredErr(err error) bool {
@@ -155,16 +330,15 @@ func isAuthRequiredErr(err error) bool {
 }
 
 func newV3Backend(opts Options) (*v3Backend, error) {
-	timeout := time.Duration(opts.TimeoutSeconds) * time.Second
-	if timeout <= 0 {
-		timeout = 5 * time.Second
-	}
+	timeouts := opts.timeouts()
 
 	scheme := "http"
 	var tlsCfg *tls.Config
 
-	if opts.TLSEnabled {
-		scheme = "https"
+	if opts.UseTLS() {
+		if opts.TLSEnabled {
+			scheme = "https"
+		}
 		// #nosec G402 — InsecureSkipVerify is an explicit opt-in via config
 		tlsCfg = &tls.Config{InsecureSkipVerify: opts.TLSSkipVerify} //nolint:gosec
 
@@ -189,11 +363,18 @@ func newV3Backend(opts Options) (*v3Backend, error) {
 		}
 	}
 
+	urls, err := endpointURLs(opts, scheme)
+	if err != nil {
+		return nil, err
+	}
+	p := &lastPeer{urls: urls}
+
 	cfg := clientv3.Config{
-		Endpoints:   []string{fmt.Sprintf("%s://%s:%s", scheme, opts.Host, opts.Port)},
-		DialTimeout: timeout,
+		Endpoints:   urls,
+		DialTimeout: timeouts.request,
 		Logger:      zap.NewNop(),
 		TLS:         tlsCfg,
+		DialOptions: []grpc.DialOption{grpc.WithChainUnaryInterceptor(p.unaryInterceptor())},
 	}
 	if opts.Username != "" {
 		cfg.Username = opts.Username
@@ -204,10 +385,15 @@ func newV3Backend(opts Options) (*v3Backend, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &v3Backend{cli: clientv3.NewKV(c), c: c, timeout: timeout}, nil
+	maxTxnOps := opts.MaxTxnOps
+	if maxTxnOps <= 0 {
+		maxTxnOps = 128
+	}
+	return &v3Backend{cli: clientv3.NewKV(c), c: c, timeouts: timeouts, maxTxnOps: maxTxnOps, lastPeer: p}, nil
 }
 
 func (b *v3Backend) proto() string { return "v3" }
+func (b *v3Backend) close() error  { return b.c.Close() }
 
 const dirMarker = ".dir"
 
@@ -232,26 +418,82 @@ func withTrail(p string) string {
 	return p + "/"
 }
 
-func (b *v3Backend) ls(directory string) ([]*Node, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
-	defer cancel()
+// lsPage caps how many keys one ls request asks for.
+const lsPage = 500
 
+// ls reads keys only and jumps over the subtree of every child directory
+// as soon as its first key is seen: the next request starts at the end of
+// the child's prefix range, so nothing else below it is fetched. Requests
+// start at one key and double their limit (up to lsPage) while they find
+// only direct keys, so every subdirectory costs one round trip and a run
+// of plain keys a few. Values are left out (Node.Lazy). Every request has
+// its own timeout and is read at the revision of the first.
+func (b *v3Backend) ls(ctx context.Context, directory string) ([]*Node, error) {
 	prefix := withTrail(directory)
-	resp, err := b.cli.Get(ctx, prefix, clientv3.WithPrefix())
-	if err != nil {
-		return nil, err
+	end := clientv3.GetPrefixRangeEnd(prefix)
+	rev := b.pinned()
+	var (
+		kvs       []*mvccpb.KeyValue
+		clusterID string
+	)
+	limit := int64(1)
+	for from := prefix; ; {
+		opts := []clientv3.OpOption{clientv3.WithRange(end), clientv3.WithKeysOnly(), clientv3.WithLimit(limit)}
+		if rev > 0 {
+			opts = append(opts, clientv3.WithRev(rev))
+		}
+		rctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+		resp, err := b.cli.Get(rctx, from, opts...)
+		cancel()
+		if err != nil {
+			return nil, v3revErr(err, rev)
+		}
+		clusterID = fmt.Sprintf("%d", resp.Header.GetClusterId())
+		if rev == 0 {
+			rev = resp.Header.GetRevision()
+		}
+
+		more, dir := resp.More, false
+		for i, kv := range resp.Kvs {
+			k := string(kv.Key)
+			kvs = append(kvs, kv)
+			rest := k[len(prefix):]
+			if j := strings.IndexByte(rest, '/'); j > 0 {
+				// one key stands for the directory; skip the rest of it
+				from = clientv3.GetPrefixRangeEnd(prefix + rest[:j+1])
+				more = more || i < len(resp.Kvs)-1
+				dir = true
+				break
+			}
+			from = k + "\x00"
+		}
+		if !more || len(resp.Kvs) == 0 {
+			break
+		}
+		if dir {
+			limit = 1
+		} else {
+			limit = min(2*limit, lsPage)
+		}
 	}
 
-	clusterID := fmt.Sprintf("%d", resp.Header.GetClusterId())
+	nodes := lsNodes(prefix, kvs, clusterID)
+	for _, n := range nodes {
+		n.Lazy = !n.IsDir
+	}
+	return nodes, nil
+}
 
+// lsNodes turns the kvs found under prefix into its direct children: a
+// directory for every child with keys below it and a node for every key.
+func lsNodes(prefix string, kvs []*mvccpb.KeyValue, clusterID string) []*Node {
 	type childInfo struct {
-		isDir     bool
-		hasFile   bool
-		fileValue string
+		isDir  bool
+		fileKV *mvccpb.KeyValue
 	}
 	children := map[string]*childInfo{}
 
-	for _, kv := range resp.Kvs {
+	for _, kv := range kvs {
 		key := normPath(string(kv.Key))
 		rest := strings.TrimPrefix(key, prefix)
 		rest = strings.TrimLeft(rest, "/")
@@ -271,8 +513,7 @@ func (b *v3Backend) ls(directory string) ([]*Node, error) {
 		if len(parts) == 2 {
 			ci.isDir = true
 		} else {
-			ci.hasFile = true
-			ci.fileValue = string(kv.Value)
+			ci.fileKV = kv
 		}
 	}
 
@@ -290,37 +531,31 @@ func (b *v3Backend) ls(directory string) ([]*Node, error) {
 		if ci.isDir {
 			nodes = append(nodes, &Node{Name: full, IsDir: true, ClusterId: clusterID})
 		}
-		if ci.hasFile {
-			nodes = append(nodes, &Node{Name: full, IsDir: false, Value: ci.fileValue, ClusterId: clusterID})
+		if ci.fileKV != nil {
+			nodes = append(nodes, v3Node(full, ci.fileKV, clusterID))
 		}
 	}
-	return nodes, nil
+	return nodes
 }
 
-func (b *v3Backend) get(key string) (*Node, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+func (b *v3Backend) get(ctx context.Context, key string) (*Node, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
 	defer cancel()
 
 	k := normPath(key)
 
-	exact, err := b.cli.Get(ctx, k)
+	exact, err := b.cli.Get(ctx, k, b.readOpts()...)
 	if err != nil {
-		return nil, err
+		return nil, v3revErr(err, b.pinned())
 	}
 	if exact.Count > 0 {
-		kv := exact.Kvs[0]
-		return &Node{
-			Name:      k,
-			IsDir:     false,
-			Value:     string(kv.Value),
-			ClusterId: fmt.Sprintf("%d", exact.Header.GetClusterId()),
-		}, nil
+		return v3Node(k, exact.Kvs[0], fmt.Sprintf("%d", exact.Header.GetClusterId())), nil
 	}
 
 	pfx := withTrail(k)
-	dirProbe, err := b.cli.Get(ctx, pfx, clientv3.WithPrefix(), clientv3.WithLimit(1))
+	dirProbe, err := b.cli.Get(ctx, pfx, b.readOpts(clientv3.WithPrefix(), clientv3.WithKeysOnly(), clientv3.WithLimit(1))...)
 	if err != nil {
-		return nil, err
+		return nil, v3revErr(err, b.pinned())
 	}
 	if dirProbe.Count > 0 {
 		return &Node{
@@ -330,112 +565,124 @@ func (b *v3Backend) get(key string) (*Node, error) {
 		}, nil
 	}
 
-	return nil, fmt.Errorf("not found: %s", k)
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, k)
+}
+
+// v3Node builds a key node carrying the kv's MVCC metadata.
+func v3Node(name string, kv *mvccpb.KeyValue, clusterID string) *Node {
+	return &Node{
+		Name:           name,
+		ClusterId:      clusterID,
+		Value:          string(kv.Value),
+		CreateRevision: kv.CreateRevision,
+		ModRevision:    kv.ModRevision,
+		Version:        kv.Version,
+		Lease:          kv.Lease,
+	}
 }
 
-func (b *v3Backend) set(key, value string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+func (b *v3Backend) set(ctx context.Context, key, value string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
 	defer cancel()
 	_, err := b.cli.Put(ctx, normPath(key), value)
 	return err
 }
 
-func (b *v3Backend) mkdir(directory string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+func (b *v3Backend) mkdir(ctx context.Context, directory string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
 	defer cancel()
 	dir := normPath(directory)
 	_, err := b.cli.Put(ctx, dir+"/"+dirMarker, "")
 	return err
 }
 
-func (b *v3Backend) del(key string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+func (b *v3Backend) del(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
 	defer cancel()
 	_, err := b.cli.Delete(ctx, normPath(key))
 	return err
 }
 
-func (b *v3Backend) deldir(key string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout*2)
-	defer cancel()
-	_, err := b.cli.Delete(ctx, withTrail(key), clientv3.WithPrefix())
-	return err
-}
-
-func (b *v3Backend) renameDir(oldDir, newDir string) error {
-	timeout := b.timeout * 4
-	if timeout < 20*time.Second {
-		timeout = 20 * time.Second
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	oldPfx := withTrail(oldDir)
-	newPfx := withTrail(newDir)
+// deldir removes the subtree in one request when it fits in a transaction.
+// Larger subtrees go in batches of maxTxnOps keys; ctx is only checked
+// between batches, so every key is either known deleted or untouched.
+func (b *v3Backend) deldir(ctx context.Context, key string, progress func(done, total int)) error {
+	prefix := withTrail(key)
 
-	resp, err := b.cli.Get(ctx, oldPfx, clientv3.WithPrefix())
+	lctx, cancel := context.WithTimeout(ctx, b.timeouts.scan)
+	resp, err := b.cli.Get(lctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	cancel()
 	if err != nil {
 		return err
 	}
+	total := len(resp.Kvs)
 
-	for _, kv := range resp.Kvs {
-		newKey := newPfx + strings.TrimPrefix(string(kv.Key), oldPfx)
-		if _, err := b.cli.Put(ctx, newKey, string(kv.Value)); err != nil {
+	if total <= b.maxTxnOps {
+		ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+		defer cancel()
+		if _, err := b.cli.Delete(ctx, prefix, clientv3.WithPrefix()); err != nil {
 			return err
 		}
+		report(progress, total, total)
+		return nil
 	}
-	_, err = b.cli.Delete(ctx, oldPfx, clientv3.WithPrefix())
-	return err
-}
-
-func (b *v3Backend) renameKey(oldKey, newKey string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout*2)
-	defer cancel()
 
-	old := normPath(oldKey)
-	resp, err := b.cli.Get(ctx, old)
-	if err != nil {
-		return err
-	}
-	if resp.Count == 0 {
-		return fmt.Errorf("key not found: %s", old)
+	deleted := make([]string, 0, total)
+	stopped := func(err error) error {
+		if len(deleted) == 0 {
+			return err
+		}
+		return &DeleteError{Deleted: deleted, Total: total, Err: err}
 	}
-	value := string(resp.Kvs[0].Value)
-	if _, err := b.cli.Put(ctx, normPath(newKey), value); err != nil {
-		return err
+	report(progress, 0, total)
+	for start := 0; start < total; start += b.maxTxnOps {
+		if err := ctx.Err(); err != nil {
+			return stopped(err)
+		}
+		batch := resp.Kvs[start:min(start+b.maxTxnOps, total)]
+		ops := make([]clientv3.Op, 0, len(batch))
+		for _, kv := range batch {
+			ops = append(ops, clientv3.OpDelete(string(kv.Key)))
+		}
+		// a batch already sent is never abandoned: cancelling it would
+		// leave its outcome unknown
+		tctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.timeouts.bulk)
+		_, err := b.cli.Txn(tctx).Then(ops...).Commit()
+		cancel()
+		if err != nil {
+			return stopped(err)
+		}
+		for _, kv := range batch {
+			deleted = append(deleted, string(kv.Key))
+		}
+		report(progress, len(deleted), total)
 	}
-	_, err = b.cli.Delete(ctx, old)
-	return err
-}
 
-func (b *v3Backend) export(dir string) (map[string]string, error) {
-	timeout := b.timeout * 10
-	if timeout < 30*time.Second {
-		timeout = 30 * time.Second
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	// keys created under the prefix while the batches ran
+	ctx, cancel = context.WithTimeout(ctx, b.timeouts.request)
 	defer cancel()
-
-	prefix := withTrail(dir)
-	resp, err := b.cli.Get(ctx, prefix, clientv3.WithPrefix())
-	if err != nil {
-		return nil, err
+	if _, err := b.cli.Delete(ctx, prefix, clientv3.WithPrefix()); err != nil {
+		return stopped(err)
 	}
+	return nil
+}
 
-	result := make(map[string]string, len(resp.Kvs))
-	for _, kv := range resp.Kvs {
-		key := string(kv.Key)
-		// skip the synthetic directory marker key (.dir)
-		if strings.HasSuffix(key, "/"+dirMarker) {
-			continue
+func (b *v3Backend) export(ctx context.Context, dir string, progress func(done, total int)) (map[string]string, error) {
+	result := make(map[string]string)
+	err := b.walk(ctx, dir, false, func(nodes []*Node, done, total int) {
+		for _, n := range nodes {
+			result[n.Name] = n.Value
 		}
-		result[key] = string(kv.Value)
+		report(progress, done, total)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return result, nil
 }
 
-func (b *v3Backend) authStatus() (bool, bool, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (b *v3Backend) authStatus(ctx context.Context) (bool, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, min(b.timeouts.request, probeTimeout))
 	defer cancel()
 
 	resp, err := b.c.Auth.AuthStatus(ctx)
@@ -453,19 +700,24 @@ func (b *v3Backend) authStatus() (bool, bool, error) {
 }
 
 type v2Backend struct {
-	api     clientv2.KeysAPI
-	client  clientv2.Client
-	timeout time.Duration
+	api      clientv2.KeysAPI
+	client   clientv2.Client
+	timeouts timeouts
+	*lastPeer
 }
 
 func newV2Backend(opts Options) (*v2Backend, error) {
-	timeout := time.Duration(opts.TimeoutSeconds) * time.Second
-	if timeout <= 0 {
-		timeout = 5 * time.Second
+	timeouts := opts.timeouts()
+
+	urls, err := endpointURLs(opts, "http")
+	if err != nil {
+		return nil, err
 	}
+	p := &lastPeer{urls: urls}
 
 	cfg := clientv2.Config{
-		Endpoints: []string{fmt.Sprintf("http://%s:%s", opts.Host, opts.Port)},
+		Endpoints: urls,
+		Transport: peerTransport{CancelableTransport: clientv2.DefaultTransport, peer: p},
 	}
 	if opts.Username != "" {
 		cfg.Username = opts.Username
@@ -476,13 +728,14 @@ func newV2Backend(opts Options) (*v2Backend, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &v2Backend{api: clientv2.NewKeysAPI(cli), client: cli, timeout: timeout}, nil
+	return &v2Backend{api: clientv2.NewKeysAPI(cli), client: cli, timeouts: timeouts, lastPeer: p}, nil
 }
 
 func (b *v2Backend) proto() string { return "v2" }
+func (b *v2Backend) close() error  { return nil }
 
-func (b *v2Backend) ls(directory string) ([]*Node, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+func (b *v2Backend) ls(ctx context.Context, directory string) ([]*Node, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
 	defer cancel()
 	resp, err := b.api.Get(ctx, directory,
 		&clientv2.GetOptions{Sort: true, Recursive: false})
@@ -495,73 +748,81 @@ func (b *v2Backend) ls(directory string) ([]*Node, error) {
 
 	var nds []*Node
 	for _, n := range resp.Node.Nodes {
-		nds = append(nds, &Node{
-			Name:      n.Key,
-			ClusterId: resp.ClusterID,
-			IsDir:     n.Dir,
-			Value:     n.Value,
-		})
+		nds = append(nds, v2Node(n, resp.ClusterID))
 	}
 	return nds, nil
 }
 
-func (b *v2Backend) get(key string) (*Node, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+// v2Node builds a node carrying the v2 index and TTL metadata.
+func v2Node(n *clientv2.Node, clusterID string) *Node {
+	return &Node{
+		Name:          n.Key,
+		ClusterId:     clusterID,
+		IsDir:         n.Dir,
+		Value:         n.Value,
+		CreatedIndex:  n.CreatedIndex,
+		ModifiedIndex: n.ModifiedIndex,
+		Expiration:    n.Expiration,
+		TTL:           n.TTL,
+	}
+}
+
+func (b *v2Backend) get(ctx context.Context, key string) (*Node, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
 	defer cancel()
 	resp, err := b.api.Get(ctx, normPath(key), nil)
 	if err != nil {
+		if clientv2.IsKeyNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, normPath(key))
+		}
 		return nil, err
 	}
-	return &Node{
-		Name:      resp.Node.Key,
-		ClusterId: resp.ClusterID,
-		IsDir:     resp.Node.Dir,
-		Value:     resp.Node.Value,
-	}, nil
+	return v2Node(resp.Node, resp.ClusterID), nil
 }
 
-func (b *v2Backend) set(key, value string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+func (b *v2Backend) set(ctx context.Context, key, value string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
 	defer cancel()
 	_, err := b.api.Set(ctx, normPath(key), value, nil)
 	return err
 }
 
-func (b *v2Backend) mkdir(directory string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+func (b *v2Backend) mkdir(ctx context.Context, directory string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
 	defer cancel()
 	_, err := b.api.Set(ctx, normPath(directory), "",
 		&clientv2.SetOptions{Dir: true, PrevExist: clientv2.PrevIgnore})
 	return err
 }
 
-func (b *v2Backend) del(key string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+func (b *v2Backend) del(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
 	defer cancel()
 	_, err := b.api.Delete(ctx, normPath(key), nil)
 	return err
 }
 
-func (b *v2Backend) deldir(key string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout*2)
+// deldir is a single recursive delete. It reports no progress and is not
+// cancelled with ctx: an abandoned request might still have been applied.
+func (b *v2Backend) deldir(ctx context.Context, key string, _ func(done, total int)) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.timeouts.scan)
 	defer cancel()
 	_, err := b.api.Delete(ctx, normPath(key),
 		&clientv2.DeleteOptions{Dir: true, Recursive: true})
 	return err
 }
 
-func (b *v2Backend) renameDir(oldDir, newDir string) error {
-	timeout := b.timeout * 4
-	if timeout < 20*time.Second {
-		timeout = 20 * time.Second
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+func (b *v2Backend) renameDir(ctx context.Context, oldDir, newDir string, progress func(done, total int)) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.bulk)
 	defer cancel()
 
 	resp, err := b.api.Get(ctx, oldDir, &clientv2.GetOptions{Recursive: true})
 	if err != nil {
 		return err
 	}
+	keys := map[string]string{}
+	v2collectKeys(resp.Node.Nodes, keys)
+	total := len(keys)
 
 	// Always create the target directory explicitly first.
 	// Without this an empty source directory would simply vanish after the
@@ -571,37 +832,51 @@ func (b *v2Backend) renameDir(oldDir, newDir string) error {
 		return err
 	}
 
-	if err := b.v2CopyNodes(ctx, resp.Node.Nodes, oldDir, newDir); err != nil {
-		return err
+	var copied []string
+	report(progress, 0, total)
+	err = b.v2CopyNodes(ctx, resp.Node.Nodes, oldDir, newDir, func(key string) {
+		copied = append(copied, key)
+		report(progress, len(copied), total)
+	})
+	if err != nil {
+		if len(copied) == 0 {
+			return err
+		}
+		return &MoveError{Copied: copied, Total: total, Err: err}
 	}
 
 	_, err = b.api.Delete(ctx, oldDir, &clientv2.DeleteOptions{Dir: true, Recursive: true})
 	return err
 }
 
-// v2CopyNodes recursively copies nodes from oldDir prefix to newDir prefix.
-func (b *v2Backend) v2CopyNodes(ctx context.Context, nodes clientv2.Nodes, oldDir, newDir string) error {
+// v2CopyNodes recursively copies nodes from oldDir prefix to newDir prefix,
+// calling copied with each new key written.
+func (b *v2Backend) v2CopyNodes(ctx context.Context, nodes clientv2.Nodes, oldDir, newDir string, copied func(string)) error {
 	for _, n := range nodes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		newKey := newDir + strings.TrimPrefix(n.Key, oldDir)
 		if n.Dir {
 			if _, err := b.api.Set(ctx, newKey, "",
 				&clientv2.SetOptions{Dir: true, PrevExist: clientv2.PrevIgnore}); err != nil {
 				return err
 			}
-			if err := b.v2CopyNodes(ctx, n.Nodes, oldDir, newDir); err != nil {
+			if err := b.v2CopyNodes(ctx, n.Nodes, oldDir, newDir, copied); err != nil {
 				return err
 			}
 		} else {
 			if _, err := b.api.Set(ctx, newKey, n.Value, nil); err != nil {
 				return err
 			}
+			copied(newKey)
 		}
 	}
 	return nil
 }
 
-func (b *v2Backend) renameKey(oldKey, newKey string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), b.timeout*2)
+func (b *v2Backend) renameKey(ctx context.Context, oldKey, newKey string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.bulk)
 	defer cancel()
 	resp, err := b.api.Get(ctx, normPath(oldKey), nil)
 	if err != nil {
@@ -614,12 +889,8 @@ func (b *v2Backend) renameKey(oldKey, newKey string) error {
 	return err
 }
 
-func (b *v2Backend) export(dir string) (map[string]string, error) {
-	timeout := b.timeout * 10
-	if timeout < 30*time.Second {
-		timeout = 30 * time.Second
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+func (b *v2Backend) export(ctx context.Context, dir string, progress func(done, total int)) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.scan)
 	defer cancel()
 
 	resp, err := b.api.Get(ctx, normPath(dir), &clientv2.GetOptions{Recursive: true})
@@ -631,6 +902,7 @@ func (b *v2Backend) export(dir string) (map[string]string, error) {
 	}
 	result := make(map[string]string)
 	v2collectKeys(resp.Node.Nodes, result)
+	report(progress, len(result), len(result))
 	return result, nil
 }
 
@@ -644,7 +916,7 @@ func v2collectKeys(nodes clientv2.Nodes, result map[string]string) {
 	}
 }
 
-func (b *v2Backend) authStatus() (enabled bool, known bool, err error) {
+func (b *v2Backend) authStatus(ctx context.Context) (enabled bool, known bool, err error) {
 	cfg := clientv2.Config{
 		Endpoints: b.client.Endpoints(),
 	}
@@ -655,11 +927,7 @@ func (b *v2Backend) authStatus() (enabled bool, known bool, err error) {
 	}
 	api := clientv2.NewKeysAPI(cli)
 
-	timeout := b.timeout
-	if timeout > 3*time.Second {
-		timeout = 3 * time.Second
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, min(b.timeouts.request, probeTimeout))
 	defer cancel()
 
 	_, err = api.Get(ctx, "/", nil)
@@ -0,0 +1,289 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	clientv2 "github.com/coreos/etcd/client"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ErrTargetExists is returned when a move would overwrite existing keys
+// and overwriting was not allowed.
+var ErrTargetExists = errors.New("target already exists")
+
+// MovePlan describes a rename before it is carried out so the caller can
+// confirm overwrites or a non-atomic move. It is only valid for the
+// backend that produced it.
+type MovePlan struct {
+	From, To string
+	IsDir    bool
+	Keys     int      // source keys to move, including .dir markers
+	Batches  int      // transactions needed (v3); 0 when not transactional
+	Existing []string // destination keys that already exist
+	Atomic   bool     // whole move happens in one step
+
+	kvs []moveKV // v3: source keys as read by planMove
+}
+
+type moveKV struct {
+	key, value string
+	modRev     int64
+	lease      int64
+}
+
+// MoveError reports a move that stopped part-way. On v3 every key is
+// either fully moved or untouched and Moved lists the source keys already
+// moved. On v2 the source is only deleted at the end, so a stopped move
+// has moved nothing; Copied lists the target keys already written.
+type MoveError struct {
+	Moved  []string
+	Copied []string
+	Total  int
+	Err    error
+}
+
+func (e *MoveError) Error() string {
+	if len(e.Moved) == 0 && len(e.Copied) > 0 {
+		return fmt.Sprintf("copied %d of %d keys before stopping, source left intact: %v", len(e.Copied), e.Total, e.Err)
+	}
+	return fmt.Sprintf("moved %d of %d keys before stopping: %v", len(e.Moved), e.Total, e.Err)
+}
+
+func (e *MoveError) Unwrap() error { return e.Err }
+
+// Stepwise reports whether the move runs as several requests that can stop
+// part-way: more than one v3 transaction, or a v2 directory copied key by
+// key. A single v2 key is written and its source deleted right after.
+func (p *MovePlan) Stepwise() bool {
+	if p.Batches > 0 {
+		return p.Batches > 1
+	}
+	return p.IsDir
+}
+
+// PlanMove reads the source (and the destination) of a rename.
+func (m *Model) PlanMove(ctx context.Context, from, to string, isDir bool) (*MovePlan, error) {
+	from, to = normPath(from), normPath(to)
+	if from == to {
+		return nil, fmt.Errorf("source and target are the same: %s", from)
+	}
+	if isDir && strings.HasPrefix(withTrail(to), withTrail(from)) {
+		return nil, fmt.Errorf("cannot move %s into itself (%s)", from, to)
+	}
+	return m.backend.planMove(ctx, from, to, isDir)
+}
+
+// Move executes a plan from PlanMove. Unless overwrite is set it fails with
+// ErrTargetExists if destination keys exist. A failure or a cancelled ctx
+// after some keys were moved is returned as *MoveError. progress, if not
+// nil, is called with the keys moved so far.
+func (m *Model) Move(ctx context.Context, p *MovePlan, overwrite bool, progress func(done, total int)) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.move(ctx, p, overwrite, progress)
+}
+
+// RenameDir moves a directory, refusing to overwrite existing keys.
+func (m *Model) RenameDir(ctx context.Context, oldDir, newDir string) error {
+	return m.rename(ctx, oldDir, newDir, true)
+}
+
+// RenameKey moves a single key, refusing to overwrite an existing one.
+func (m *Model) RenameKey(ctx context.Context, oldKey, newKey string) error {
+	return m.rename(ctx, oldKey, newKey, false)
+}
+
+func (m *Model) rename(ctx context.Context, from, to string, isDir bool) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	p, err := m.PlanMove(ctx, from, to, isDir)
+	if err != nil {
+		return err
+	}
+	return m.Move(ctx, p, false, nil)
+}
+
+// keysPerTxn is how many keys fit in one move transaction: each key costs
+// a put and a delete in the success branch and up to two compares.
+func (b *v3Backend) keysPerTxn() int {
+	n := b.maxTxnOps / 2
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func (b *v3Backend) planMove(ctx context.Context, from, to string, isDir bool) (*MovePlan, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.bulk)
+	defer cancel()
+
+	p := &MovePlan{From: from, To: to, IsDir: isDir}
+
+	var src, dst *clientv3.GetResponse
+	var err error
+	if isDir {
+		src, err = b.cli.Get(ctx, withTrail(from), clientv3.WithPrefix())
+		if err == nil {
+			dst, err = b.cli.Get(ctx, withTrail(to), clientv3.WithPrefix(), clientv3.WithKeysOnly())
+		}
+	} else {
+		src, err = b.cli.Get(ctx, from)
+		if err == nil {
+			dst, err = b.cli.Get(ctx, to, clientv3.WithKeysOnly())
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(src.Kvs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, from)
+	}
+
+	for _, kv := range src.Kvs {
+		p.kvs = append(p.kvs, moveKV{
+			key:    string(kv.Key),
+			value:  string(kv.Value),
+			modRev: kv.ModRevision,
+			lease:  kv.Lease,
+		})
+	}
+	for _, kv := range dst.Kvs {
+		p.Existing = append(p.Existing, string(kv.Key))
+	}
+
+	per := b.keysPerTxn()
+	p.Keys = len(p.kvs)
+	p.Batches = (p.Keys + per - 1) / per
+	p.Atomic = p.Batches == 1
+	return p, nil
+}
+
+// move runs the plan in batches of keysPerTxn keys. ctx is only checked
+// between batches, so a cancelled move knows exactly what it moved.
+func (b *v3Backend) move(ctx context.Context, p *MovePlan, overwrite bool, progress func(done, total int)) error {
+	if !overwrite && len(p.Existing) > 0 {
+		return fmt.Errorf("%w: %d key(s) under %s", ErrTargetExists, len(p.Existing), p.To)
+	}
+
+	target := func(key string) string {
+		if !p.IsDir {
+			return p.To
+		}
+		return withTrail(p.To) + strings.TrimPrefix(key, withTrail(p.From))
+	}
+
+	per := b.keysPerTxn()
+	moved := make([]string, 0, len(p.kvs))
+	stopped := func(err error) error {
+		if len(moved) == 0 {
+			return err
+		}
+		return &MoveError{Moved: moved, Total: len(p.kvs), Err: err}
+	}
+	report(progress, 0, len(p.kvs))
+	for start := 0; start < len(p.kvs); start += per {
+		if err := ctx.Err(); err != nil {
+			return stopped(err)
+		}
+		end := start + per
+		if end > len(p.kvs) {
+			end = len(p.kvs)
+		}
+
+		var cmps []clientv3.Cmp
+		var ops []clientv3.Op
+		for _, kv := range p.kvs[start:end] {
+			dst := target(kv.key)
+			// the source must be exactly what we planned with ...
+			cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(kv.key), "=", kv.modRev))
+			// ... and the destination must not have appeared meanwhile
+			if !overwrite {
+				cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(dst), "=", 0))
+			}
+			var putOpts []clientv3.OpOption
+			if kv.lease != 0 {
+				putOpts = append(putOpts, clientv3.WithLease(clientv3.LeaseID(kv.lease)))
+			}
+			ops = append(ops, clientv3.OpPut(dst, kv.value, putOpts...), clientv3.OpDelete(kv.key))
+		}
+
+		// a batch already sent is never abandoned: cancelling it would
+		// leave its outcome unknown
+		tctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.timeouts.bulk)
+		resp, err := b.cli.Txn(tctx).If(cmps...).Then(ops...).Commit()
+		cancel()
+		if err == nil && !resp.Succeeded {
+			err = fmt.Errorf("%w: keys changed since the move was planned", ErrConflict)
+		}
+		if err != nil {
+			return stopped(err)
+		}
+		for _, kv := range p.kvs[start:end] {
+			moved = append(moved, kv.key)
+		}
+		report(progress, len(moved), len(p.kvs))
+	}
+	return nil
+}
+
+func (b *v2Backend) planMove(ctx context.Context, from, to string, isDir bool) (*MovePlan, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.bulk)
+	defer cancel()
+
+	p := &MovePlan{From: from, To: to, IsDir: isDir}
+
+	src, err := b.api.Get(ctx, from, &clientv2.GetOptions{Recursive: isDir})
+	if err != nil {
+		if clientv2.IsKeyNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, from)
+		}
+		return nil, err
+	}
+	if isDir {
+		keys := map[string]string{}
+		v2collectKeys(src.Node.Nodes, keys)
+		p.Keys = len(keys)
+	} else {
+		p.Keys = 1
+	}
+
+	dst, err := b.api.Get(ctx, to, &clientv2.GetOptions{Recursive: true})
+	switch {
+	case err == nil && dst.Node.Dir:
+		keys := map[string]string{}
+		v2collectKeys(dst.Node.Nodes, keys)
+		for k := range keys {
+			p.Existing = append(p.Existing, k)
+		}
+		if len(p.Existing) == 0 {
+			p.Existing = []string{dst.Node.Key}
+		}
+	case err == nil:
+		p.Existing = []string{dst.Node.Key}
+	case !clientv2.IsKeyNotFound(err):
+		return nil, err
+	}
+	return p, nil
+}
+
+func (b *v2Backend) move(ctx context.Context, p *MovePlan, overwrite bool, progress func(done, total int)) error {
+	if !overwrite && len(p.Existing) > 0 {
+		return fmt.Errorf("%w: %s", ErrTargetExists, p.To)
+	}
+	// v2 has no transactions: keys are copied first and the source is
+	// deleted only after every copy succeeded, so a failure leaves the
+	// source intact (plus a partial copy at the target).
+	if p.IsDir {
+		return b.renameDir(ctx, p.From, p.To, progress)
+	}
+	if err := b.renameKey(ctx, p.From, p.To); err != nil {
+		return err
+	}
+	report(progress, 1, 1)
+	return nil
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var (
+	// ErrReadOnly is returned by every write while the model refuses writes.
+	ErrReadOnly = errors.New("read-only")
+	// ErrCompacted means the requested revision is older than the
+	// cluster's compaction boundary and its data is gone.
+	ErrCompacted = errors.New("revision has been compacted")
+	// ErrFutureRevision means the requested revision does not exist yet.
+	ErrFutureRevision = errors.New("revision is in the future")
+	// ErrNotSupported is returned for v3-only features on other backends.
+	ErrNotSupported = errors.New("not supported by this backend")
+)
+
+// PinRevision makes ls/get/export read the keyspace as of rev and refuses
+// all writes until it is reset with PinRevision(0).
+func (m *Model) PinRevision(ctx context.Context, rev int64) error { return m.backend.pin(ctx, rev) }
+
+// PinnedRevision returns the pinned revision, or 0 when reading the latest.
+func (m *Model) PinnedRevision() int64 { return m.backend.pinned() }
+
+// HeadRevision returns the cluster's current revision (v3 only).
+func (m *Model) HeadRevision(ctx context.Context) (int64, error) { return m.backend.headRevision(ctx) }
+
+// ReadOnly reports whether the model was opened with Options.ReadOnly.
+func (m *Model) ReadOnly() bool { return m.readOnly }
+
+// writable returns a non-nil error when writes must be refused.
+func (m *Model) writable() error {
+	if m.readOnly {
+		return fmt.Errorf("%w: read-only mode", ErrReadOnly)
+	}
+	if rev := m.backend.pinned(); rev != 0 {
+		return fmt.Errorf("%w: browsing revision %d", ErrReadOnly, rev)
+	}
+	return nil
+}
+
+// revPin holds the revision reads are pinned to; it is shared by the UI
+// goroutine and background loaders, hence atomic.
+type revPin struct{ rev atomic.Int64 }
+
+func (p *revPin) pinned() int64 { return p.rev.Load() }
+
+// readOpts appends WithRev to opts when a revision is pinned.
+func (b *v3Backend) readOpts(opts ...clientv3.OpOption) []clientv3.OpOption {
+	if rev := b.pinned(); rev > 0 {
+		opts = append(opts, clientv3.WithRev(rev))
+	}
+	return opts
+}
+
+func (b *v3Backend) pin(ctx context.Context, rev int64) error {
+	if rev < 0 {
+		return fmt.Errorf("invalid revision %d", rev)
+	}
+	if rev > 0 {
+		ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+		defer cancel()
+		_, err := b.cli.Get(ctx, "/", clientv3.WithRev(rev), clientv3.WithCountOnly())
+		if err != nil {
+			return v3revErr(err, rev)
+		}
+	}
+	b.rev.Store(rev)
+	return nil
+}
+
+func (b *v3Backend) headRevision(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	resp, err := b.cli.Get(ctx, "/", clientv3.WithCountOnly())
+	if err != nil {
+		return 0, err
+	}
+	return resp.Header.GetRevision(), nil
+}
+
+// v3revErr maps the server's revision errors onto the model's sentinels.
+func v3revErr(err error, rev int64) error {
+	switch {
+	case errors.Is(err, rpctypes.ErrCompacted):
+		return fmt.Errorf("%w: %d", ErrCompacted, rev)
+	case errors.Is(err, rpctypes.ErrFutureRev):
+		return fmt.Errorf("%w: %d", ErrFutureRevision, rev)
+	}
+	return err
+}
+
+func (b *v2Backend) pin(ctx context.Context, rev int64) error {
+	if rev == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: revision browsing requires etcd v3", ErrNotSupported)
+}
+
+func (b *v2Backend) pinned() int64 { return 0 }
+
+func (b *v2Backend) headRevision(ctx context.Context) (int64, error) {
+	return 0, fmt.Errorf("%w: revisions require etcd v3", ErrNotSupported)
+}
@@ -0,0 +1,241 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	clientv2 "github.com/coreos/etcd/client"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// SearchMode decides how Search matches its pattern.
+type SearchMode int
+
+const (
+	SearchSubstring SearchMode = iota
+	SearchGlob
+	SearchRegex
+)
+
+func (m SearchMode) String() string {
+	switch m {
+	case SearchGlob:
+		return "glob"
+	case SearchRegex:
+		return "regex"
+	}
+	return "substring"
+}
+
+// SearchTarget decides what Search matches the pattern against.
+type SearchTarget int
+
+const (
+	SearchPathsAndValues SearchTarget = iota
+	SearchPaths
+	SearchValues
+)
+
+func (t SearchTarget) String() string {
+	switch t {
+	case SearchPaths:
+		return "paths"
+	case SearchValues:
+		return "values"
+	}
+	return "paths and values"
+}
+
+// SearchQuery controls Search.
+type SearchQuery struct {
+	Pattern    string
+	Mode       SearchMode
+	Target     SearchTarget
+	IgnoreCase bool
+}
+
+// SearchHit is a key that matched a query.
+type SearchHit struct {
+	Key     string
+	Value   string // empty when only paths were searched
+	InPath  bool
+	InValue bool
+}
+
+// Search scans every key below dir for q. found is called after every
+// batch read with the hits in it and the keys scanned so far out of the
+// total, so results can be shown while the scan goes on. Cancelling ctx
+// stops the scan.
+func (m *Model) Search(ctx context.Context, dir string, q SearchQuery, found func(hits []SearchHit, done, total int)) error {
+	match, err := q.matcher()
+	if err != nil {
+		return err
+	}
+	return m.backend.walk(ctx, dir, q.Target == SearchPaths, func(nodes []*Node, done, total int) {
+		var hits []SearchHit
+		for _, n := range nodes {
+			h := SearchHit{
+				Key:     n.Name,
+				InPath:  q.Target != SearchValues && match(n.Name),
+				InValue: q.Target != SearchPaths && match(n.Value),
+			}
+			if h.InPath || h.InValue {
+				h.Value = n.Value
+				hits = append(hits, h)
+			}
+		}
+		found(hits, done, total)
+	})
+}
+
+// matcher compiles the pattern of q.
+func (q SearchQuery) matcher() (func(string) bool, error) {
+	if q.Pattern == "" {
+		return nil, errors.New("empty search pattern")
+	}
+	switch q.Mode {
+	case SearchGlob, SearchRegex:
+		expr := q.Pattern
+		if q.Mode == SearchGlob {
+			expr = globRegexp(q.Pattern)
+		}
+		if q.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", q.Mode, q.Pattern, err)
+		}
+		return re.MatchString, nil
+	}
+	if q.IgnoreCase {
+		p := strings.ToLower(q.Pattern)
+		return func(s string) bool { return strings.Contains(strings.ToLower(s), p) }, nil
+	}
+	return func(s string) bool { return strings.Contains(s, q.Pattern) }, nil
+}
+
+// globRegexp turns a shell glob into an anchored regular expression.
+// Unlike path.Match, * and ? also match '/', so "*host*" finds the word
+// anywhere in a path.
+func globRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteString(`(?s)^`)
+	for i := 0; i < len(glob); i++ {
+		switch ch := glob[i]; ch {
+		case '*':
+			sb.WriteString(`.*`)
+		case '?':
+			sb.WriteString(`.`)
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	sb.WriteString(`$`)
+	return sb.String()
+}
+
+// walkPage is how many keys walk reads per request.
+const walkPage = 1000
+
+// walk reads every key below dir in pages, all at the revision of the
+// first one (or the pinned revision), so the keys form a consistent view.
+// The .dir markers are skipped; done and total count every key read.
+func (b *v3Backend) walk(ctx context.Context, dir string, keysOnly bool, visit func(nodes []*Node, done, total int)) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.scan)
+	defer cancel()
+
+	prefix := withTrail(dir)
+	end := clientv3.GetPrefixRangeEnd(prefix)
+	rev := b.pinned()
+
+	var done, total int
+	for from := prefix; ; {
+		opts := []clientv3.OpOption{clientv3.WithRange(end), clientv3.WithLimit(walkPage)}
+		if keysOnly {
+			opts = append(opts, clientv3.WithKeysOnly())
+		}
+		if rev > 0 {
+			opts = append(opts, clientv3.WithRev(rev))
+		}
+		resp, err := b.cli.Get(ctx, from, opts...)
+		if err != nil {
+			return v3revErr(err, rev)
+		}
+		if rev == 0 {
+			rev = resp.Header.GetRevision()
+		}
+		if done == 0 {
+			// later pages count only what is left of the range
+			total = int(resp.Count)
+		}
+
+		clusterID := fmt.Sprintf("%d", resp.Header.GetClusterId())
+		nodes := make([]*Node, 0, len(resp.Kvs))
+		for _, kv := range resp.Kvs {
+			key := string(kv.Key)
+			// skip the synthetic directory marker key (.dir)
+			if strings.HasSuffix(key, "/"+dirMarker) {
+				continue
+			}
+			n := v3Node(key, kv, clusterID)
+			n.Lazy = keysOnly
+			nodes = append(nodes, n)
+		}
+		done += len(resp.Kvs)
+		visit(nodes, done, total)
+
+		if !resp.More || len(resp.Kvs) == 0 {
+			return nil
+		}
+		from = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+}
+
+// walk reads the subtree with one recursive request and visits it at once.
+func (b *v2Backend) walk(ctx context.Context, dir string, _ bool, visit func(nodes []*Node, done, total int)) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.scan)
+	defer cancel()
+
+	resp, err := b.api.Get(ctx, normPath(dir), &clientv2.GetOptions{Recursive: true, Sort: true})
+	if err != nil {
+		if clientv2.IsKeyNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	var nodes []*Node
+	var collect func(clientv2.Nodes)
+	collect = func(ns clientv2.Nodes) {
+		for _, n := range ns {
+			if n.Dir {
+				collect(n.Nodes)
+			} else {
+				nodes = append(nodes, v2Node(n, resp.ClusterID))
+			}
+		}
+	}
+	collect(resp.Node.Nodes)
+	visit(nodes, len(nodes), len(nodes))
+	return nil
+}
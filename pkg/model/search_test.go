@@ -0,0 +1,94 @@
+package model
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		glob string
+		want string
+	}{
+		{"*", `(?s)^.*$`},
+		{"a?c", `(?s)^a.c$`},
+		{"*.conf", `(?s)^.*\.conf$`},
+		{"c++", `(?s)^c\+\+$`},
+		{"[abc]x", `(?s)^[abc]x$`},
+		{"[!abc]x", `(?s)^[^abc]x$`},
+		{"[a-z]*", `(?s)^[a-z].*$`},
+		{"[open", `(?s)^\[open$`},
+		{`\*`, `(?s)^\*$`},
+		{`\?`, `(?s)^\?$`},
+		{`a\`, `(?s)^a\\$`},
+	}
+	for _, tt := range tests {
+		got := globRegexp(tt.glob)
+		if got != tt.want {
+			t.Errorf("globRegexp(%q) = %s, want %s", tt.glob, got, tt.want)
+		}
+		if _, err := regexp.Compile(got); err != nil {
+			t.Errorf("globRegexp(%q) = %s does not compile: %v", tt.glob, got, err)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		q    SearchQuery
+		in   string
+		want bool
+	}{
+		{SearchQuery{Pattern: "host", Mode: SearchSubstring}, "/app/db/host", true},
+		{SearchQuery{Pattern: "HOST", Mode: SearchSubstring}, "/app/db/host", false},
+		{SearchQuery{Pattern: "HOST", Mode: SearchSubstring, IgnoreCase: true}, "/app/db/host", true},
+		{SearchQuery{Pattern: "GRÖSSE", Mode: SearchSubstring, IgnoreCase: true}, "/app/grösse", true},
+
+		// * and ? cross '/', and the glob is anchored
+		{SearchQuery{Pattern: "*host*", Mode: SearchGlob}, "/app/db/host/x", true},
+		{SearchQuery{Pattern: "host", Mode: SearchGlob}, "/app/db/host", false},
+		{SearchQuery{Pattern: "/app?db/host", Mode: SearchGlob}, "/app/db/host", true},
+		{SearchQuery{Pattern: "/app/?", Mode: SearchGlob}, "/app/ö", true},
+		{SearchQuery{Pattern: "/app/?", Mode: SearchGlob}, "/app/ab", false},
+		{SearchQuery{Pattern: "*", Mode: SearchGlob}, "line 1\nline 2", true},
+		// . and + are literal
+		{SearchQuery{Pattern: "*.conf", Mode: SearchGlob}, "/etc/app.conf", true},
+		{SearchQuery{Pattern: "*.conf", Mode: SearchGlob}, "/etc/appxconf", false},
+		{SearchQuery{Pattern: "*/c++", Mode: SearchGlob}, "/lang/c++", true},
+		{SearchQuery{Pattern: "*/c++", Mode: SearchGlob}, "/lang/cc", false},
+		// classes and escapes
+		{SearchQuery{Pattern: "/node[0-9]", Mode: SearchGlob}, "/node7", true},
+		{SearchQuery{Pattern: "/node[!0-9]", Mode: SearchGlob}, "/node7", false},
+		{SearchQuery{Pattern: "/node[!0-9]", Mode: SearchGlob}, "/nodex", true},
+		{SearchQuery{Pattern: "/[open", Mode: SearchGlob}, "/[open", true},
+		{SearchQuery{Pattern: `/a\*`, Mode: SearchGlob}, "/a*", true},
+		{SearchQuery{Pattern: `/a\*`, Mode: SearchGlob}, "/ab", false},
+		{SearchQuery{Pattern: "/APP/*", Mode: SearchGlob, IgnoreCase: true}, "/app/x", true},
+
+		{SearchQuery{Pattern: `^/app/\w+$`, Mode: SearchRegex}, "/app/db", true},
+		{SearchQuery{Pattern: `^/app/\w+$`, Mode: SearchRegex}, "/app/db/host", false},
+		{SearchQuery{Pattern: `DB`, Mode: SearchRegex, IgnoreCase: true}, "/app/db", true},
+	}
+	for _, tt := range tests {
+		match, err := tt.q.matcher()
+		if err != nil {
+			t.Errorf("%s %q: %v", tt.q.Mode, tt.q.Pattern, err)
+			continue
+		}
+		if got := match(tt.in); got != tt.want {
+			t.Errorf("%s %q (ignore case %v) on %q = %v, want %v", tt.q.Mode, tt.q.Pattern, tt.q.IgnoreCase, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatcherErrors(t *testing.T) {
+	for _, q := range []SearchQuery{
+		{Pattern: "", Mode: SearchSubstring},
+		{Pattern: "(", Mode: SearchRegex},
+		{Pattern: "[z-a]", Mode: SearchGlob},
+	} {
+		if _, err := q.matcher(); err == nil {
+			t.Errorf("%s %q: got no error", q.Mode, q.Pattern)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package model
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// SaveSnapshot streams a snapshot of the cluster's backend database
+// (Maintenance.Snapshot, v3) to path. The data goes to path.part first and
+// is renamed into place once complete. progress, if set, is called as
+// bytes arrive with the total expected (the member's DB size, 0 if
+// unknown).
+func (m *Model) SaveSnapshot(ctx context.Context, path string, progress func(done, total int64)) (int64, error) {
+	tmp := path + ".part"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	n, err := m.backend.snapshot(ctx, f, progress)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return n, nil
+}
+
+// progressWriter reports the bytes written through it.
+type progressWriter struct {
+	w     io.Writer
+	done  int64
+	total int64
+	fn    func(done, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	if p.fn != nil {
+		p.fn(p.done, p.total)
+	}
+	return n, err
+}
+
+func (b *v3Backend) snapshot(ctx context.Context, w io.Writer, progress func(done, total int64)) (int64, error) {
+	pw := &progressWriter{w: w, fn: progress}
+	sctx, scancel := context.WithTimeout(ctx, b.timeouts.request)
+	if st, err := b.c.Status(sctx, b.endpoint()); err == nil {
+		pw.total = st.DbSize
+	}
+	scancel()
+
+	// no timeout: a large database takes as long as it takes, unless the
+	// caller gives up
+	rc, err := b.c.Snapshot(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	_, err = io.Copy(pw, rc)
+	return pw.done, err
+}
+
+func (b *v2Backend) snapshot(context.Context, io.Writer, func(int64, int64)) (int64, error) {
+	return 0, fmt.Errorf("%w: snapshots require etcd v3", ErrNotSupported)
+}
+
+// snapBackend serves an etcd v3 backend database (a snapshot or a
+// member's member/snap/db) read-only, without a running etcd. Opening the
+// file indexes the revisions of every live key; values are read from the
+// file on demand.
+type snapBackend struct {
+	path string
+	db   *bolt.DB
+	rev  int64               // newest revision in the file
+	revs map[string][]revKey // revisions of each live key since it was created, oldest first
+	keys []string            // sorted keys of revs
+}
+
+var keyBucket = []byte("key")
+
+var (
+	errSnapshotRO = fmt.Errorf("%w: snapshot files are read-only", ErrReadOnly)
+	errSnapshotNA = fmt.Errorf("%w: not available for snapshot files", ErrNotSupported)
+)
+
+// revision keys are 8 bytes main revision, '_', 8 bytes sub revision,
+// followed by 't' for a deletion (tombstone).
+const revKeyLen = 17
+
+// revKey is the bucket key of a revision that put a value.
+type revKey [revKeyLen]byte
+
+func (rk revKey) main() int64 { return int64(binary.BigEndian.Uint64(rk[:8])) }
+
+func openSnapshot(path string) (*snapBackend, error) {
+	db, err := bolt.Open(path, 0o400, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	b := &snapBackend{path: path, db: db, revs: map[string][]revKey{}}
+	err = db.View(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(keyBucket)
+		if bkt == nil {
+			return fmt.Errorf("%s is not an etcd v3 database (no %q bucket)", path, keyBucket)
+		}
+		// revisions are stored in order; a tombstone ends a key's history
+		return bkt.ForEach(func(k, v []byte) error {
+			if len(k) < revKeyLen {
+				return nil
+			}
+			b.rev = int64(binary.BigEndian.Uint64(k[:8]))
+			key, err := kvKey(v)
+			if err != nil {
+				return fmt.Errorf("revision %d: %w", b.rev, err)
+			}
+			if len(k) > revKeyLen && k[revKeyLen] == 't' {
+				delete(b.revs, string(key))
+				return nil
+			}
+			var rk revKey
+			copy(rk[:], k)
+			b.revs[string(key)] = append(b.revs[string(key)], rk)
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	b.keys = make([]string, 0, len(b.revs))
+	for k := range b.revs {
+		b.keys = append(b.keys, k)
+	}
+	sort.Strings(b.keys)
+	return b, nil
+}
+
+// kvKey returns the key field of a marshalled mvccpb.KeyValue without
+// decoding the rest of it.
+func kvKey(v []byte) ([]byte, error) {
+	for len(v) > 0 {
+		tag, n := binary.Uvarint(v)
+		if n <= 0 {
+			return nil, errors.New("malformed key value")
+		}
+		v = v[n:]
+		switch tag & 7 {
+		case 0: // varint
+			if _, n = binary.Uvarint(v); n <= 0 {
+				return nil, errors.New("malformed key value")
+			}
+			v = v[n:]
+		case 2: // length-delimited
+			l, n := binary.Uvarint(v)
+			if n <= 0 || l > uint64(len(v)-n) {
+				return nil, errors.New("malformed key value")
+			}
+			if tag>>3 == 1 {
+				return v[n : n+int(l)], nil
+			}
+			v = v[n+int(l):]
+		default:
+			return nil, fmt.Errorf("malformed key value (wire type %d)", tag&7)
+		}
+	}
+	return nil, errors.New("key value without a key")
+}
+
+// read loads the kvs stored at revs in one read transaction and passes
+// them to fn with their index, checking ctx every walkPage revisions.
+func (b *snapBackend) read(ctx context.Context, revs []revKey, fn func(i int, kv *mvccpb.KeyValue)) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(keyBucket)
+		for i, rk := range revs {
+			if i%walkPage == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			kv := &mvccpb.KeyValue{}
+			if err := kv.Unmarshal(bkt.Get(rk[:])); err != nil {
+				return fmt.Errorf("revision %d: %w", rk.main(), err)
+			}
+			fn(i, kv)
+		}
+		return nil
+	})
+}
+
+// clusterID is shown in place of the cluster ID, which the file lacks.
+func (b *snapBackend) clusterID() string { return "snapshot " + filepath.Base(b.path) }
+
+// withPrefix returns the live keys under prefix in order.
+func (b *snapBackend) withPrefix(prefix string) []string {
+	i := sort.SearchStrings(b.keys, prefix)
+	j := i
+	for j < len(b.keys) && strings.HasPrefix(b.keys[j], prefix) {
+		j++
+	}
+	return b.keys[i:j]
+}
+
+func (b *snapBackend) proto() string { return "snapshot" }
+
+// ls lists keys only, like the v3 backend; Model.Load reads a value once
+// it is shown.
+func (b *snapBackend) ls(_ context.Context, directory string) ([]*Node, error) {
+	prefix := withTrail(directory)
+	keys := b.withPrefix(prefix)
+	kvs := make([]*mvccpb.KeyValue, len(keys))
+	for i, k := range keys {
+		kvs[i] = &mvccpb.KeyValue{Key: []byte(k)}
+	}
+	nodes := lsNodes(prefix, kvs, b.clusterID())
+	for _, n := range nodes {
+		n.Lazy = !n.IsDir
+	}
+	return nodes, nil
+}
+
+func (b *snapBackend) get(ctx context.Context, key string) (*Node, error) {
+	k := normPath(key)
+	if revs, ok := b.revs[k]; ok {
+		var n *Node
+		err := b.read(ctx, revs[len(revs)-1:], func(_ int, kv *mvccpb.KeyValue) {
+			n = v3Node(k, kv, b.clusterID())
+		})
+		return n, err
+	}
+	pfx := withTrail(k)
+	if i := sort.SearchStrings(b.keys, pfx); i < len(b.keys) && strings.HasPrefix(b.keys[i], pfx) {
+		return &Node{Name: k, IsDir: true, ClusterId: b.clusterID()}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, k)
+}
+
+func (b *snapBackend) export(ctx context.Context, dir string, progress func(done, total int)) (map[string]string, error) {
+	result := make(map[string]string)
+	err := b.walk(ctx, dir, false, func(nodes []*Node, done, total int) {
+		for _, n := range nodes {
+			result[n.Name] = n.Value
+		}
+		report(progress, done, total)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// walk visits the subtree walkPage keys at a time, reading values unless
+// keysOnly is set.
+func (b *snapBackend) walk(ctx context.Context, dir string, keysOnly bool, visit func(nodes []*Node, done, total int)) error {
+	var keys []string
+	for _, k := range b.withPrefix(withTrail(dir)) {
+		if !strings.HasSuffix(k, "/"+dirMarker) {
+			keys = append(keys, k)
+		}
+	}
+	for from := 0; from < len(keys); from += walkPage {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page := keys[from:min(from+walkPage, len(keys))]
+		nodes := make([]*Node, len(page))
+		if keysOnly {
+			for i, k := range page {
+				nodes[i] = &Node{Name: k, ClusterId: b.clusterID(), Lazy: true}
+			}
+		} else {
+			revs := make([]revKey, len(page))
+			for i, k := range page {
+				r := b.revs[k]
+				revs[i] = r[len(r)-1]
+			}
+			err := b.read(ctx, revs, func(i int, kv *mvccpb.KeyValue) {
+				nodes[i] = v3Node(page[i], kv, b.clusterID())
+			})
+			if err != nil {
+				return err
+			}
+		}
+		visit(nodes, from+len(page), len(keys))
+	}
+	return nil
+}
+
+func (b *snapBackend) headRevision(context.Context) (int64, error) { return b.rev, nil }
+
+// history reads the indexed versions of key since it was created; versions
+// older than the last compaction are gone, as on a live cluster.
+func (b *snapBackend) history(ctx context.Context, key string, limit int) ([]KeyVersion, bool, error) {
+	k := normPath(key)
+	revs, ok := b.revs[k]
+	if !ok {
+		return nil, false, fmt.Errorf("key %w: %s", ErrNotFound, k)
+	}
+	truncated := false
+	if limit > 0 && len(revs) > limit {
+		revs, truncated = revs[len(revs)-limit:], true
+	}
+	versions := make([]KeyVersion, len(revs))
+	err := b.read(ctx, revs, func(i int, kv *mvccpb.KeyValue) {
+		// newest first
+		versions[len(revs)-1-i] = KeyVersion{Value: string(kv.Value), ModRevision: kv.ModRevision, Version: kv.Version}
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(versions) > 0 && versions[len(versions)-1].Version > 1 {
+		truncated = true
+	}
+	return versions, truncated, nil
+}
+
+func (b *snapBackend) watch(context.Context, string) (<-chan []WatchEvent, func()) {
+	// the file never changes; the channel only closes when stopped
+	out := make(chan []WatchEvent)
+	var once sync.Once
+	return out, func() { once.Do(func() { close(out) }) }
+}
+
+func (b *snapBackend) endpoint() string    { return b.path }
+func (b *snapBackend) endpoints() []string { return []string{b.path} }
+func (b *snapBackend) close() error        { return b.db.Close() }
+func (b *snapBackend) pinned() int64       { return 0 }
+
+func (b *snapBackend) authStatus(context.Context) (bool, bool, error) { return false, false, nil }
+
+func (b *snapBackend) remainingTTL(context.Context, *Node) (int64, error) { return 0, nil }
+
+func (b *snapBackend) pin(context.Context, int64) error { return errSnapshotNA }
+func (b *snapBackend) snapshot(context.Context, io.Writer, func(int64, int64)) (int64, error) {
+	return 0, errSnapshotNA
+}
+func (b *snapBackend) clusterStatus(context.Context) (*ClusterStatus, error) {
+	return nil, errSnapshotNA
+}
+func (b *snapBackend) leases(context.Context) ([]Lease, error)      { return nil, errSnapshotNA }
+func (b *snapBackend) lease(context.Context, int64) (*Lease, error) { return nil, errSnapshotNA }
+func (b *snapBackend) users(context.Context) ([]User, error)        { return nil, errSnapshotNA }
+func (b *snapBackend) roles(context.Context) ([]Role, error)        { return nil, errSnapshotNA }
+
+func (b *snapBackend) set(context.Context, string, string) error { return errSnapshotRO }
+func (b *snapBackend) mkdir(context.Context, string) error       { return errSnapshotRO }
+func (b *snapBackend) del(context.Context, string) error         { return errSnapshotRO }
+func (b *snapBackend) deldir(context.Context, string, func(int, int)) error {
+	return errSnapshotRO
+}
+func (b *snapBackend) planMove(context.Context, string, string, bool) (*MovePlan, error) {
+	return nil, errSnapshotRO
+}
+func (b *snapBackend) move(context.Context, *MovePlan, bool, func(int, int)) error {
+	return errSnapshotRO
+}
+func (b *snapBackend) putMany(context.Context, map[string]importKV, func(done, total int)) ([]string, error) {
+	return nil, errSnapshotRO
+}
+func (b *snapBackend) setIfUnchanged(context.Context, string, string, int64) error {
+	return errSnapshotRO
+}
+func (b *snapBackend) grantLease(context.Context, int64) (int64, error)        { return 0, errSnapshotRO }
+func (b *snapBackend) revokeLease(context.Context, int64) error                { return errSnapshotRO }
+func (b *snapBackend) attachLease(context.Context, string, int64, int64) error { return errSnapshotRO }
+func (b *snapBackend) setWithLease(context.Context, string, string, int64, int64) error {
+	return errSnapshotRO
+}
+func (b *snapBackend) setWithTTL(context.Context, string, string, int64) error { return errSnapshotRO }
+func (b *snapBackend) mkdirWithTTL(context.Context, string, int64) error       { return errSnapshotRO }
+func (b *snapBackend) setTTL(context.Context, *Node, int64) error              { return errSnapshotRO }
+func (b *snapBackend) refreshTTL(context.Context, *Node, int64) error          { return errSnapshotRO }
+func (b *snapBackend) setAuth(context.Context, bool) error                     { return errSnapshotRO }
+func (b *snapBackend) addUser(context.Context, string, string) error           { return errSnapshotRO }
+func (b *snapBackend) deleteUser(context.Context, string) error                { return errSnapshotRO }
+func (b *snapBackend) changePassword(context.Context, string, string) error    { return errSnapshotRO }
+func (b *snapBackend) grantRole(context.Context, string, string) error         { return errSnapshotRO }
+func (b *snapBackend) revokeRole(context.Context, string, string) error        { return errSnapshotRO }
+func (b *snapBackend) addRole(context.Context, string) error                   { return errSnapshotRO }
+func (b *snapBackend) deleteRole(context.Context, string) error                { return errSnapshotRO }
+func (b *snapBackend) grantPermission(context.Context, string, Permission) error {
+	return errSnapshotRO
+}
+func (b *snapBackend) revokePermission(context.Context, string, Permission) error {
+	return errSnapshotRO
+}
+func (b *snapBackend) compact(context.Context, int64) error                { return errSnapshotRO }
+func (b *snapBackend) defragment(context.Context, string) error            { return errSnapshotRO }
+func (b *snapBackend) hashKV(context.Context, int64) ([]MemberHash, error) { return nil, errSnapshotNA }
+func (b *snapBackend) alarms(context.Context) ([]Alarm, error)             { return nil, errSnapshotNA }
+func (b *snapBackend) disarmAlarms(context.Context) error                  { return errSnapshotRO }
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// timeouts bound the requests a backend makes. They are applied on top of
+// the caller's context, which can always end a request sooner.
+type timeouts struct {
+	request     time.Duration // a single read or write
+	scan        time.Duration // a whole subtree, a key's history, every lease
+	bulk        time.Duration // imports and each batch of a move or subtree delete
+	maintenance time.Duration // compaction and defragmentation walk the whole database
+}
+
+// probeTimeout caps the auth probe made when connecting.
+const probeTimeout = 3 * time.Second
+
+// timeouts resolves the configured timeouts, filling in the defaults.
+func (o Options) timeouts() timeouts {
+	seconds := func(n int) time.Duration { return time.Duration(n) * time.Second }
+	t := timeouts{
+		request:     seconds(o.TimeoutSeconds),
+		scan:        seconds(o.ScanTimeoutSeconds),
+		bulk:        seconds(o.BulkTimeoutSeconds),
+		maintenance: seconds(o.MaintenanceTimeoutSeconds),
+	}
+	if t.request <= 0 {
+		t.request = 5 * time.Second
+	}
+	if t.scan <= 0 {
+		t.scan = max(t.request*10, 30*time.Second)
+	}
+	if t.bulk <= 0 {
+		t.bulk = max(t.request*4, 20*time.Second)
+	}
+	if t.maintenance <= 0 {
+		t.maintenance = max(t.request, 5*time.Minute)
+	}
+	return t
+}
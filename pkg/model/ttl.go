@@ -0,0 +1,198 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	clientv2 "github.com/coreos/etcd/client"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// SetWithTTL writes key so that it expires ttl seconds from now: on v3
+// under a freshly granted lease, on v2 through SetOptions.TTL. A ttl of 0
+// is the same as Set.
+func (m *Model) SetWithTTL(ctx context.Context, key, value string, ttl int64) error {
+	if ttl == 0 {
+		return m.Set(ctx, key, value)
+	}
+	if err := m.writable(); err != nil {
+		return err
+	}
+	if ttl < 0 {
+		return fmt.Errorf("invalid TTL %d", ttl)
+	}
+	return m.backend.setWithTTL(ctx, key, value, ttl)
+}
+
+// MkDirWithTTL creates a directory that expires ttl seconds from now. On
+// v3 only the directory marker is leased; keys created below it stay.
+func (m *Model) MkDirWithTTL(ctx context.Context, directory string, ttl int64) error {
+	if ttl == 0 {
+		return m.MkDir(ctx, directory)
+	}
+	if err := m.writable(); err != nil {
+		return err
+	}
+	if ttl < 0 {
+		return fmt.Errorf("invalid TTL %d", ttl)
+	}
+	return m.backend.mkdirWithTTL(ctx, directory, ttl)
+}
+
+// RemainingTTL returns the seconds left before n expires, 0 if it never
+// does. On v3 this asks for the TTL of the node's lease.
+func (m *Model) RemainingTTL(ctx context.Context, n *Node) (int64, error) {
+	return m.backend.remainingTTL(ctx, n)
+}
+
+// SetTTL makes n expire ttl seconds from now, or never when ttl is 0,
+// keeping its value. The write only happens if the key is still at
+// n.Revision(). On v3 the key moves to a new lease; the old one is left
+// alone, as other keys may still be attached to it.
+func (m *Model) SetTTL(ctx context.Context, n *Node, ttl int64) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	if ttl < 0 {
+		return fmt.Errorf("invalid TTL %d", ttl)
+	}
+	return m.backend.setTTL(ctx, n, ttl)
+}
+
+// RefreshTTL restarts the countdown of n. On v3 its lease is kept alive
+// once, back to the granted TTL, which also refreshes every other key on
+// that lease; ttl is ignored. On v2 this is a Refresh to ttl seconds,
+// which does not notify watchers.
+func (m *Model) RefreshTTL(ctx context.Context, n *Node, ttl int64) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+	return m.backend.refreshTTL(ctx, n, ttl)
+}
+
+func (b *v3Backend) setWithTTL(ctx context.Context, key, value string, ttl int64) error {
+	id, err := b.grantLease(ctx, ttl)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	if _, err := b.cli.Put(ctx, normPath(key), value, clientv3.WithLease(clientv3.LeaseID(id))); err != nil {
+		_ = b.revokeLease(ctx, id)
+		return err
+	}
+	return nil
+}
+
+func (b *v3Backend) mkdirWithTTL(ctx context.Context, directory string, ttl int64) error {
+	return b.setWithTTL(ctx, normPath(directory)+"/"+dirMarker, "", ttl)
+}
+
+func (b *v3Backend) remainingTTL(ctx context.Context, n *Node) (int64, error) {
+	if n.Lease == 0 {
+		return 0, nil
+	}
+	l, err := b.lease(ctx, n.Lease)
+	if err != nil {
+		return 0, err
+	}
+	if l.TTL < 0 {
+		// expired; the key is about to go away
+		return 0, nil
+	}
+	return l.TTL, nil
+}
+
+func (b *v3Backend) setTTL(ctx context.Context, n *Node, ttl int64) error {
+	var id int64
+	if ttl != 0 {
+		var err error
+		if id, err = b.grantLease(ctx, ttl); err != nil {
+			return err
+		}
+	}
+	if err := b.attachLease(ctx, n.Name, id, n.Revision()); err != nil {
+		if id != 0 {
+			_ = b.revokeLease(ctx, id)
+		}
+		return err
+	}
+	return nil
+}
+
+func (b *v3Backend) refreshTTL(ctx context.Context, n *Node, _ int64) error {
+	if n.Lease == 0 {
+		return fmt.Errorf("%s has no TTL", n.Name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err := b.c.KeepAliveOnce(ctx, clientv3.LeaseID(n.Lease))
+	return err
+}
+
+func (b *v2Backend) setWithTTL(ctx context.Context, key, value string, ttl int64) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err := b.api.Set(ctx, normPath(key), value,
+		&clientv2.SetOptions{TTL: time.Duration(ttl) * time.Second})
+	return err
+}
+
+func (b *v2Backend) mkdirWithTTL(ctx context.Context, directory string, ttl int64) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err := b.api.Set(ctx, normPath(directory), "",
+		&clientv2.SetOptions{Dir: true, PrevExist: clientv2.PrevIgnore, TTL: time.Duration(ttl) * time.Second})
+	return err
+}
+
+func (b *v2Backend) remainingTTL(ctx context.Context, n *Node) (int64, error) {
+	if n.Expiration == nil {
+		return 0, nil
+	}
+	return v2TTLLeft(*n.Expiration), nil
+}
+
+func (b *v2Backend) setTTL(ctx context.Context, n *Node, ttl int64) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+
+	k := normPath(n.Name)
+	var err error
+	if ttl == 0 {
+		// Refresh cannot drop a TTL; a set without one does, so write the
+		// value we were shown back (guarded by its index).
+		_, err = b.api.Set(ctx, k, n.Value, &clientv2.SetOptions{PrevIndex: n.ModifiedIndex})
+	} else {
+		_, err = b.api.Set(ctx, k, "", &clientv2.SetOptions{
+			PrevIndex: n.ModifiedIndex,
+			TTL:       time.Duration(ttl) * time.Second,
+			Refresh:   true,
+		})
+	}
+	var cerr clientv2.Error
+	if errors.As(err, &cerr) {
+		switch cerr.Code {
+		case clientv2.ErrorCodeTestFailed, clientv2.ErrorCodeKeyNotFound:
+			return fmt.Errorf("%w: %s", ErrConflict, k)
+		}
+	}
+	return err
+}
+
+func (b *v2Backend) refreshTTL(ctx context.Context, n *Node, ttl int64) error {
+	if ttl <= 0 {
+		return fmt.Errorf("refresh needs a TTL of at least one second")
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.timeouts.request)
+	defer cancel()
+	_, err := b.api.Set(ctx, normPath(n.Name), "", &clientv2.SetOptions{
+		Dir:       n.IsDir,
+		PrevExist: clientv2.PrevExist,
+		TTL:       time.Duration(ttl) * time.Second,
+		Refresh:   true,
+	})
+	return err
+}
@@ -0,0 +1,137 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	clientv2 "github.com/coreos/etcd/client"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// WatchEvent is a single change observed under a watched directory.
+type WatchEvent struct {
+	Key     string
+	Deleted bool
+
+	// Resync marks a watch that was re-established after changes may have
+	// been missed (compaction, a dropped v2 watcher); Key is empty and the
+	// directory has to be read again.
+	Resync bool
+}
+
+// watchRetry is how long a broken watch waits before it is re-established.
+const watchRetry = 2 * time.Second
+
+// Watch streams changes made anywhere under directory until stop is called.
+// Every receive carries the events delivered by one etcd response; the
+// channel is closed once the watch has been stopped.
+func (m *Model) Watch(ctx context.Context, directory string) (events <-chan []WatchEvent, stop func()) {
+	return m.backend.watch(ctx, directory)
+}
+
+func (b *v3Backend) watch(ctx context.Context, directory string) (<-chan []WatchEvent, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	out := make(chan []WatchEvent, 16)
+	prefix := withTrail(directory)
+
+	go func() {
+		defer close(out)
+		var next int64 // first revision not seen yet; 0 before the first response
+		resync := false
+		for ctx.Err() == nil {
+			opts := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithCreatedNotify()}
+			if next > 0 {
+				// pick up where the broken watch stopped
+				opts = append(opts, clientv3.WithRev(next))
+			}
+			wctx, wcancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+			for resp := range b.c.Watch(wctx, prefix, opts...) {
+				if err := resp.Err(); err != nil {
+					if resp.CompactRevision != 0 {
+						// the missed revisions are gone: watch from now on
+						// and have the caller re-read the directory
+						next, resync = 0, true
+					}
+					break
+				}
+				if resp.Header.Revision > 0 {
+					next = resp.Header.Revision + 1
+				}
+				var batch []WatchEvent
+				if resp.Created && resync {
+					batch, resync = append(batch, WatchEvent{Resync: true}), false
+				}
+				for _, ev := range resp.Events {
+					batch = append(batch, WatchEvent{
+						Key:     string(ev.Kv.Key),
+						Deleted: ev.Type == clientv3.EventTypeDelete,
+					})
+					next = ev.Kv.ModRevision + 1
+				}
+				if len(batch) == 0 {
+					continue
+				}
+				select {
+				case out <- batch:
+				case <-ctx.Done():
+					wcancel()
+					return
+				}
+			}
+			wcancel()
+			select {
+			case <-time.After(watchRetry):
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out, cancel
+}
+
+func (b *v2Backend) watch(ctx context.Context, directory string) (<-chan []WatchEvent, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	out := make(chan []WatchEvent, 16)
+	dir := normPath(directory)
+
+	go func() {
+		defer close(out)
+		w := b.api.Watcher(dir, &clientv2.WatcherOptions{Recursive: true})
+		for ctx.Err() == nil {
+			resp, err := w.Next(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				// The v2 event history only keeps the last 1000 events; once
+				// our index falls out of it (or the connection drops) the
+				// watcher has to be rebuilt from the current index.
+				select {
+				case <-time.After(watchRetry):
+				case <-ctx.Done():
+					return
+				}
+				w = b.api.Watcher(dir, &clientv2.WatcherOptions{Recursive: true})
+				select {
+				case out <- []WatchEvent{{Resync: true}}:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+			if resp.Node == nil {
+				continue
+			}
+			ev := WatchEvent{
+				Key: resp.Node.Key,
+				Deleted: resp.Action == "delete" || resp.Action == "expire" ||
+					resp.Action == "compareAndDelete",
+			}
+			select {
+			case out <- []WatchEvent{ev}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, cancel
+}
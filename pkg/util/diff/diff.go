@@ -0,0 +1,104 @@
+package diff
+
+import "strings"
+
+// Op tells whether a line is shared, only in the old text or only in the new one.
+type Op byte
+
+const (
+	Equal  Op = ' '
+	Delete Op = '-'
+	Insert Op = '+'
+)
+
+// Line is one line of a diff.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// maxCells bounds the LCS table; beyond it Lines degrades to "all old
+// lines removed, all new lines added" rather than eating memory.
+const maxCells = 4_000_000
+
+// Lines returns a line-based diff turning a into b (LCS, no context trimming).
+func Lines(a, b string) []Line {
+	x := strings.Split(a, "\n")
+	y := strings.Split(b, "\n")
+
+	// Strip the common head and tail first; values usually differ in a
+	// few lines only, which keeps the table small.
+	head := 0
+	for head < len(x) && head < len(y) && x[head] == y[head] {
+		head++
+	}
+	tail := 0
+	for tail < len(x)-head && tail < len(y)-head && x[len(x)-1-tail] == y[len(y)-1-tail] {
+		tail++
+	}
+
+	out := make([]Line, 0, len(x)+len(y))
+	for _, s := range x[:head] {
+		out = append(out, Line{Equal, s})
+	}
+	out = append(out, lcs(x[head:len(x)-tail], y[head:len(y)-tail])...)
+	for _, s := range x[len(x)-tail:] {
+		out = append(out, Line{Equal, s})
+	}
+	return out
+}
+
+func lcs(x, y []string) []Line {
+	n, m := len(x), len(y)
+	if n*m > maxCells {
+		out := make([]Line, 0, n+m)
+		for _, s := range x {
+			out = append(out, Line{Delete, s})
+		}
+		for _, s := range y {
+			out = append(out, Line{Insert, s})
+		}
+		return out
+	}
+
+	// t[i][j] = length of the LCS of x[i:] and y[j:]
+	t := make([][]int, n+1)
+	for i := range t {
+		t[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				t[i][j] = t[i+1][j+1] + 1
+			} else if t[i+1][j] >= t[i][j+1] {
+				t[i][j] = t[i+1][j]
+			} else {
+				t[i][j] = t[i][j+1]
+			}
+		}
+	}
+
+	out := make([]Line, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case x[i] == y[j]:
+			out = append(out, Line{Equal, x[i]})
+			i++
+			j++
+		case t[i+1][j] >= t[i][j+1]:
+			out = append(out, Line{Delete, x[i]})
+			i++
+		default:
+			out = append(out, Line{Insert, y[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		out = append(out, Line{Delete, x[i]})
+	}
+	for ; j < m; j++ {
+		out = append(out, Line{Insert, y[j]})
+	}
+	return out
+}
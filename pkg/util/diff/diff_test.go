@@ -0,0 +1,72 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// render writes lines one per row, prefixed by their Op.
+func render(lines []Line) string {
+	var sb strings.Builder
+	for _, l := range lines {
+		sb.WriteString(string(l.Op) + l.Text + "\n")
+	}
+	return sb.String()
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "a\nb", "a\nb", " a\n b\n"},
+		{"empty", "", "", " \n"},
+		{"from empty", "", "a", "-\n+a\n"},
+		{"changed line", "a\nb\nc", "a\nB\nc", " a\n-b\n+B\n c\n"},
+		{"insert", "a\nc", "a\nb\nc", " a\n+b\n c\n"},
+		{"delete", "a\nb\nc", "a\nc", " a\n-b\n c\n"},
+		{"append", "a", "a\nb", " a\n+b\n"},
+		{"common head and tail", "h\nx\ny\nt", "h\ny\nz\nt", " h\n-x\n y\n+z\n t\n"},
+		{"trailing newline", "a\n", "a", " a\n-\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := render(Lines(tt.a, tt.b)); got != tt.want {
+				t.Errorf("Lines(%q, %q):\n%s\nwant:\n%s", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLinesFallback checks that a diff too large for the LCS table still
+// keeps the common head and tail and replaces the middle wholesale.
+func TestLinesFallback(t *testing.T) {
+	n := 2001 // n*n > maxCells
+	var x, y []string
+	for i := 0; i < n; i++ {
+		x = append(x, fmt.Sprintf("old %d", i))
+		y = append(y, fmt.Sprintf("new %d", i))
+	}
+	a := "head\n" + strings.Join(x, "\n") + "\ntail"
+	b := "head\n" + strings.Join(y, "\n") + "\ntail"
+
+	got := Lines(a, b)
+	if len(got) != 2*n+2 {
+		t.Fatalf("got %d lines, want %d", len(got), 2*n+2)
+	}
+	if got[0] != (Line{Equal, "head"}) || got[len(got)-1] != (Line{Equal, "tail"}) {
+		t.Errorf("head and tail: got %v and %v", got[0], got[len(got)-1])
+	}
+	for i, l := range got[1 : n+1] {
+		if l != (Line{Delete, x[i]}) {
+			t.Fatalf("line %d: got %v, want %v", i+1, l, Line{Delete, x[i]})
+		}
+	}
+	for i, l := range got[n+1 : 2*n+1] {
+		if l != (Line{Insert, y[i]}) {
+			t.Fatalf("line %d: got %v, want %v", n+1+i, l, Line{Insert, y[i]})
+		}
+	}
+}
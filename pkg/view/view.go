@@ -2,19 +2,30 @@ package view
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
+// legend is the hotkey summary shown at the bottom of the frame.
+const legend = "[::b][↓,↑][::-] Down/Up  [::b][Enter/Backspace][::-]Open/Up [::b][Ctrl+N][::-]New [::b][Del[][::-]Delete [::b][Ctrl+E][::-]Edit [::b][Ctrl+R][::-]Rename [::b][/,Ctrl+S][::-]Search [::b][f][::-]Filter [::b][Ctrl+F][::-]Find [::b][Ctrl+J][::-]Jump [::b][Ctrl+T][::-]Revision [::b][Ctrl+L][::-]History [::b][Ctrl+W][::-]Export [::b][Ctrl+O][::-]Import [::b][Ctrl+G][::-]Profile [::b][Ctrl+D][::-]Cluster [::b][Ctrl+A][::-]Leases [::b][Ctrl+U][::-]Auth [::b][Ctrl+B][::-]Snapshot [::b][Ctrl+H][::-]Hotkeys [::b][Ctrl+Q][::-]Quit"
+
 // View ...
 type View struct {
 	App       *tview.Application
 	Frame     *tview.Frame
 	Pages     *tview.Pages
 	List      *tview.List
+	Filter    *tview.InputField // filter bar under List, hidden until opened
 	Details   *tview.TextView
 	ModalEdit func(p tview.Primitive, width, height int) tview.Primitive
+
+	// left stacks List and Filter.
+	left *tview.Flex
+	// editor is the page stack of the open full-screen editor, if any.
+	editor *tview.Pages
 }
 
 // NewView ...
@@ -38,8 +49,16 @@ func NewView() *View {
 		})
 	tv.SetBorder(true).SetTitle("Details")
 
+	filter := tview.NewInputField().
+		SetLabelColor(tcell.ColorYellow)
+
+	left := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(list, 0, 1, true).
+		AddItem(filter, 0, 0, false)
+
 	main := tview.NewFlex()
-	main.AddItem(list, 0, 2, true)
+	main.AddItem(left, 0, 2, true)
 	main.AddItem(tv, 0, 3, false)
 
 	pages := tview.NewPages().
@@ -56,31 +75,60 @@ func NewView() *View {
 	}
 
 	frame := tview.NewFrame(pages)
-	frame.AddText(
-		"[::b][↓,↑][::-] Down/Up  [::b][Enter/Backspace][::-]Open/Up [::b][Ctrl+N][::-]New [::b][Del[][::-]Delete [::b][Ctrl+E][::-]Edit [::b][Ctrl+R][::-]Rename [::b][/,Ctrl+S][::-]Search [::b][Ctrl+J][::-]Jump [::b][Ctrl+W][::-]Export [::b][Ctrl+H][::-]Hotkeys [::b][Ctrl+Q][::-]Quit",
-		false,
-		tview.AlignCenter,
-		tcell.ColorWhite,
-	)
+	frame.AddText(legend, false, tview.AlignCenter, tcell.ColorWhite)
 
 	app.SetRoot(frame, true)
 
 	v := View{
-		app,
-		frame,
-		pages,
-		list,
-		tv,
-		modal,
+		App:       app,
+		Frame:     frame,
+		Pages:     pages,
+		List:      list,
+		Filter:    filter,
+		Details:   tv,
+		ModalEdit: modal,
+		left:      left,
 	}
 
 	return &v
 }
 
+// SetHeader replaces the status line at the top of the frame.
+func (v *View) SetHeader(text string, color tcell.Color) {
+	v.Frame.Clear()
+	v.Frame.AddText(text, true, tview.AlignCenter, color)
+	v.Frame.AddText(legend, false, tview.AlignCenter, tcell.ColorWhite)
+}
+
+// ShowFilter opens the filter bar under the list and focuses it.
+func (v *View) ShowFilter() {
+	v.left.ResizeItem(v.Filter, 1, 0)
+	v.App.SetFocus(v.Filter)
+}
+
+// HideFilter closes the filter bar, handing the focus back to the list if
+// the bar had it.
+func (v *View) HideFilter() {
+	v.left.ResizeItem(v.Filter, 0, 0)
+	if v.Filter.HasFocus() {
+		v.App.SetFocus(v.List)
+	}
+}
+
+// SetFilterLabel labels the filter bar, in red when the text is invalid.
+func (v *View) SetFilterLabel(label string, invalid bool) {
+	color := tcell.ColorYellow
+	if invalid {
+		color = tcell.ColorRed
+	}
+	v.Filter.SetLabel(label).SetLabelColor(color)
+}
+
 func (v *View) NewCreateForm(header string) *tview.Form {
 	form := tview.NewForm().
 		AddInputField("Node name", "", 30, nil, nil).
-		AddInputField("Value", "", 30, nil, nil)
+		AddInputField("Value", "", 30, nil, nil).
+		AddInputField("TTL (seconds)", "", 10, tview.InputFieldInteger, nil)
 
 	form.AddCheckbox("Is a Directory", false, func(checked bool) {
 	})
@@ -148,6 +196,12 @@ func (v *View) NewDeleteQ(header string) *tview.Modal {
 	return deleteQ
 }
 
+func (v *View) NewConfirmQ(text string) *tview.Modal {
+	confirmQ := tview.NewModal()
+	confirmQ.SetText(text).AddButtons([]string{"ok", "cancel"})
+	return confirmQ
+}
+
 func (v *View) NewErrorMessageQ(header string, details string) *tview.Modal {
 	errorQ := tview.NewModal()
 	errorQ.SetText(header + ": " + details).SetBackgroundColor(tcell.ColorRed).AddButtons([]string{"ok"})
@@ -167,11 +221,23 @@ func (v *View) NewHotkeysModal() *tview.TextView {
 		  Ctrl+J        Jump to key/dir (dir ends with '/')
 		  Ctrl+P        Copy path (key/dir)
 		  Ctrl+Y        Copy key value
-		  Ctrl+W        Export current dir keys to JSON file
+		  Ctrl+W        Export current dir (JSON, YAML, .env, …)
+		  Ctrl+O        Import keys from a JSON export
+		  Ctrl+T        Browse at a past revision (v3, read-only)
+		  Ctrl+L        Key history: diff and restore old versions (v3)
+		  Ctrl+G        Switch cluster profile
+		  Ctrl+D        Cluster members and status (m: maintenance)
+		  Ctrl+A        Leases: TTLs, keys, grant / revoke / attach (v3)
+		  Ctrl+U        Auth: users, roles, permissions (v3)
+		  Ctrl+B        Save a snapshot of the database to a file (v3)
 		[::b]Search[::-]
 		  /, Ctrl+S     Search by name (in current level)
+		  f             Filter the listing as you type (Tab: mode, Esc: clear)
+		  Ctrl+F        Find keys below the current dir by path or value
 		[::b]Editor[::-]
 		  Ctrl+S        Save
+		  Ctrl+G        Save under a lease, or none (v3)
+		  Ctrl+T        TTL: extend / refresh / clear
 		  Esc/Ctrl+Q    Cancel/Cancel+Quit
 		[::b]Misc[::-]
 		  Ctrl+H        This help
@@ -190,10 +256,174 @@ func (v *View) NewHotkeysModal() *tview.TextView {
 	return tv
 }
 
-func (v *View) NewExportInput(dir, defaultPath string) *tview.InputField {
+// NewExportInput asks for the export format and target file. Picking a
+// format swaps the file extension via ext.
+func (v *View) NewExportInput(dir, defaultPath string, formats []string, ext func(format string) string) *tview.Form {
+	form := tview.NewForm().
+		AddInputField("File", defaultPath, 50, nil, nil)
+	path := form.GetFormItem(0).(*tview.InputField)
+	form.AddDropDown("Format", formats, 0, func(option string, _ int) {
+		cur := path.GetText()
+		if i := strings.LastIndex(cur, "."); i > strings.LastIndex(cur, "/") {
+			cur = cur[:i]
+		}
+		path.SetText(cur + ext(option))
+	})
+	form.SetBorder(true)
+	form.SetTitle(fmt.Sprintf(" Export %q keys ", dir))
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			v.Pages.RemovePage("modal")
+		}
+		return event
+	})
+	return form
+}
+
+// NewFindForm asks what to look for below dir; modes and targets are
+// listed in model.SearchMode and model.SearchTarget order.
+func (v *View) NewFindForm(dir string, modes, targets []string) *tview.Form {
+	form := tview.NewForm().
+		AddInputField("Pattern", "", 40, nil, nil).
+		AddDropDown("Mode", modes, 0, nil).
+		AddDropDown("Match", targets, 0, nil).
+		AddCheckbox("Ignore case", true, nil)
+	form.SetBorder(true)
+	form.SetTitle(fmt.Sprintf(" Find below %q ", dir))
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			v.Pages.RemovePage("modal")
+		}
+		return event
+	})
+	return form
+}
+
+func (v *View) NewImportInput(dir, defaultPath string) *tview.InputField {
 	inp := tview.NewInputField().
 		SetText(defaultPath)
-	inp.SetBorder(true).SetTitle(fmt.Sprintf(" Export %q keys to JSON file ", dir))
+	inp.SetBorder(true).SetTitle(fmt.Sprintf(" Import JSON file into %q ", dir))
+	return inp
+}
+
+// NewImportForm asks for the conflict policy and an optional prefix
+// rewrite; policies are listed in model.ConflictPolicy order.
+func (v *View) NewImportForm(header string, policies []string, from, to string) *tview.Form {
+	form := tview.NewForm().
+		AddDropDown("On conflict", policies, 0, nil).
+		AddInputField("From prefix", from, 40, nil, nil).
+		AddInputField("To prefix", to, 40, nil, nil)
+	form.SetBorder(true)
+	form.SetTitle(header)
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			v.Pages.RemovePage("modal")
+		}
+		return event
+	})
+	return form
+}
+
+func (v *View) NewSnapshotInput(defaultPath string) *tview.InputField {
+	inp := tview.NewInputField().
+		SetText(defaultPath)
+	inp.SetBorder(true).SetTitle(" Save snapshot to file ")
+	return inp
+}
+
+// NewProfilePicker lists connection profiles; the active one is starred.
+func (v *View) NewProfilePicker(names []string, current string) *tview.List {
+	list := tview.NewList().ShowSecondaryText(false)
+	for _, name := range names {
+		label := "  " + name
+		if name == current {
+			label = "* " + name
+		}
+		list.AddItem(tview.Escape(label), "", 0, nil)
+	}
+	list.SetBorder(true).SetTitle(" Switch cluster ")
+	return list
+}
+
+// NewWaitModal shows a message without buttons while work is in progress.
+func (v *View) NewWaitModal(text string) *tview.Modal {
+	return tview.NewModal().SetText(text)
+}
+
+// ProgressModal follows an operation over many keys: a bar, the keys done
+// out of the total and the rate, above a Cancel button.
+type ProgressModal struct {
+	*tview.Modal
+	text        string
+	start       time.Time
+	done, total int
+	cancelling  bool
+}
+
+// NewProgressModal shows text over a progress bar that starts empty.
+func (v *View) NewProgressModal(text string) *ProgressModal {
+	p := &ProgressModal{
+		Modal: tview.NewModal().AddButtons([]string{"Cancel"}),
+		text:  text,
+		start: time.Now(),
+	}
+	p.SetProgress(0, 0)
+	return p
+}
+
+// SetProgress shows done out of total keys; a total of 0 is not known yet.
+func (p *ProgressModal) SetProgress(done, total int) {
+	p.done, p.total = done, total
+
+	var sb strings.Builder
+	sb.WriteString(p.text + "\n\n")
+	if total > 0 {
+		const width = 30
+		frac := min(float64(done)/float64(total), 1)
+		filled := int(frac * width)
+		fmt.Fprintf(&sb, "%s%s %3.0f%%\n%d / %d keys", strings.Repeat("█", filled),
+			strings.Repeat("░", width-filled), frac*100, done, total)
+	} else {
+		fmt.Fprintf(&sb, "%d keys", done)
+	}
+	if secs := time.Since(p.start).Seconds(); done > 0 && secs >= 1 {
+		fmt.Fprintf(&sb, ", %.0f keys/s", float64(done)/secs)
+	}
+	if p.cancelling {
+		sb.WriteString("\n\ncancelling after the current batch …")
+	}
+	p.SetText(sb.String())
+}
+
+// SetCancelling drops the Cancel button and says so until the operation
+// stops.
+func (p *ProgressModal) SetCancelling() {
+	p.cancelling = true
+	p.ClearButtons()
+	p.SetProgress(p.done, p.total)
+}
+
+func (v *View) NewRevisionInput(head, pinned int64) *tview.InputField {
+	inp := tview.NewInputField().
+		SetPlaceholder("revision number; empty or 0 = latest").
+		SetAcceptanceFunc(tview.InputFieldInteger)
+	if pinned > 0 {
+		inp.SetText(fmt.Sprintf("%d", pinned))
+	}
+	inp.SetBorder(true).SetTitle(fmt.Sprintf(" Browse at revision (head: %d) ", head))
+	return inp
+}
+
+// NewCompactInput asks for the revision to compact up to, the head by
+// default.
+func (v *View) NewCompactInput(head int64) *tview.InputField {
+	inp := tview.NewInputField().
+		SetText(fmt.Sprintf("%d", head)).
+		SetAcceptanceFunc(tview.InputFieldInteger)
+	inp.SetBorder(true).SetTitle(fmt.Sprintf(" Compact up to revision (head: %d) ", head))
 	return inp
 }
 
@@ -201,22 +431,298 @@ func (v *View) NewMultilineEditor(title, initial string) *tview.TextArea {
 	ta := tview.NewTextArea().
 		SetText(initial, false). // false -> caret at beginning (first line)
 		SetPlaceholder("")
-	ta.SetBorder(true).
-		SetTitle(title + "  [Ctrl+S=Save | Esc=Cancel]")
+	ta.SetBorder(true)
+	v.RetitleEditor(ta, title)
 	return ta
 }
 
+// RetitleEditor changes the title of an editor made by NewMultilineEditor.
+func (v *View) RetitleEditor(ta *tview.TextArea, title string) {
+	ta.SetTitle(title + "  [Ctrl+S=Save | Ctrl+G=Save+lease | Ctrl+T=TTL | Esc=Cancel]")
+}
+
+// NewTTLForm shows when key expires and asks for a new TTL; the buttons
+// are added by the caller.
+func (v *View) NewTTLForm(key string, remaining int64) *tview.Form {
+	status, ttl := "never expires", ""
+	if remaining > 0 {
+		status = fmt.Sprintf("expires in %ds", remaining)
+		ttl = fmt.Sprintf("%d", remaining)
+	}
+	form := tview.NewForm().
+		AddTextView("Now", status, 30, 1, false, false).
+		AddInputField("TTL (seconds)", ttl, 10, tview.InputFieldInteger, nil)
+	form.SetBorder(true)
+	form.SetTitle(fmt.Sprintf(" TTL: %s ", tview.Escape(key)))
+	return form
+}
+
+// HistoryView lists the versions of a key on the left and shows the
+// selected value, or a diff between two versions, on the right.
+type HistoryView struct {
+	*tview.Flex
+	Versions *tview.List
+	Content  *tview.TextView
+}
+
+func (v *View) NewHistoryView(key string) *HistoryView {
+	versions := tview.NewList().ShowSecondaryText(false)
+	versions.SetBorder(true).
+		SetTitle(" History: " + key + " ").
+		SetTitleAlign(tview.AlignLeft)
+	versions.SetSelectedTextColor(tcell.ColorBlack).
+		SetSelectedBackgroundColor(tcell.ColorYellow)
+
+	content := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWordWrap(true)
+	content.SetBorder(true).
+		SetTitle(" [Space]Mark base  [d]Diff vs base  [r]Restore  [Esc]Close ")
+
+	flex := tview.NewFlex().
+		AddItem(versions, 0, 2, true).
+		AddItem(content, 0, 3, false)
+	return &HistoryView{Flex: flex, Versions: versions, Content: content}
+}
+
+// FindView lists search hits as they stream in, with the value of the
+// highlighted one on the right and the scan progress below.
+type FindView struct {
+	*tview.Flex
+	Results *tview.List
+	Value   *tview.TextView
+	Status  *tview.TextView
+}
+
+func (v *View) NewFindView(title string) *FindView {
+	results := tview.NewList().ShowSecondaryText(false)
+	results.SetBorder(true).
+		SetTitle(" " + title + " ").
+		SetTitleAlign(tview.AlignLeft)
+	results.SetSelectedTextColor(tcell.ColorBlack).
+		SetSelectedBackgroundColor(tcell.ColorYellow)
+
+	value := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWordWrap(true)
+	value.SetBorder(true).
+		SetTitle(" [Enter]Jump to key  [Esc]Close ")
+
+	status := tview.NewTextView().SetDynamicColors(true)
+
+	flex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().
+			AddItem(results, 0, 3, true).
+			AddItem(value, 0, 2, false), 0, 1, true).
+		AddItem(status, 1, 0, false)
+	return &FindView{Flex: flex, Results: results, Value: value, Status: status}
+}
+
+// ClusterView shows one row per member and the selected member's details.
+type ClusterView struct {
+	*tview.Flex
+	Members *tview.Table
+	Details *tview.TextView
+}
+
+func (v *View) NewClusterView() *ClusterView {
+	members := tview.NewTable().
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+	members.SetBorder(true).
+		SetTitle(" Cluster members ").
+		SetTitleAlign(tview.AlignLeft)
+	members.SetSelectedStyle(tcell.StyleDefault.
+		Foreground(tcell.ColorBlack).
+		Background(tcell.ColorYellow))
+
+	details := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWordWrap(true)
+	details.SetBorder(true).
+		SetTitle(" [r]Refresh  [m]Maintenance  [Esc]Close ")
+
+	flex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(members, 0, 3, true).
+		AddItem(details, 0, 2, false)
+	return &ClusterView{Flex: flex, Members: members, Details: details}
+}
+
+// LeaseView lists leases on the left and the selected lease's keys on
+// the right.
+type LeaseView struct {
+	*tview.Flex
+	Leases *tview.List
+	Keys   *tview.List
+}
+
+func (v *View) NewLeaseView() *LeaseView {
+	leases := tview.NewList().ShowSecondaryText(false)
+	leases.SetBorder(true).
+		SetTitle(" Leases  [g]Grant [a]Attach key [x]Revoke [r]Reload ").
+		SetTitleAlign(tview.AlignLeft)
+	leases.SetSelectedTextColor(tcell.ColorBlack).
+		SetSelectedBackgroundColor(tcell.ColorYellow)
+
+	keys := tview.NewList().ShowSecondaryText(false)
+	keys.SetBorder(true).
+		SetTitle(" Keys  [Enter]Jump [d]Detach [Tab]Switch [Esc]Close ").
+		SetTitleAlign(tview.AlignLeft)
+
+	flex := tview.NewFlex().
+		AddItem(leases, 0, 1, true).
+		AddItem(keys, 0, 1, false)
+	return &LeaseView{Flex: flex, Leases: leases, Keys: keys}
+}
+
+func (v *View) NewLeaseGrantInput() *tview.InputField {
+	inp := tview.NewInputField().
+		SetPlaceholder("TTL in seconds").
+		SetAcceptanceFunc(tview.InputFieldInteger)
+	inp.SetBorder(true).SetTitle(" Grant lease ")
+	return inp
+}
+
+func (v *View) NewLeaseAttachInput(lease int64, key string) *tview.InputField {
+	inp := tview.NewInputField().
+		SetText(key)
+	inp.SetBorder(true).SetTitle(fmt.Sprintf(" Attach key to lease %x ", lease))
+	return inp
+}
+
+// AuthView lists users and roles on the left and, on the right, the
+// roles of the selected user or the permissions of the selected role.
+type AuthView struct {
+	*tview.Flex
+	Users *tview.List
+	Roles *tview.List
+	Items *tview.List
+}
+
+func (v *View) NewAuthView() *AuthView {
+	users := tview.NewList().ShowSecondaryText(false)
+	users.SetBorder(true).
+		SetTitle(" Users  [n]New [p]Password [g]Grant role [x]Delete ").
+		SetTitleAlign(tview.AlignLeft)
+
+	roles := tview.NewList().ShowSecondaryText(false)
+	roles.SetBorder(true).
+		SetTitle(" Roles  [n]New [g]Grant permission [x]Delete ").
+		SetTitleAlign(tview.AlignLeft)
+
+	items := tview.NewList().ShowSecondaryText(false)
+	items.SetBorder(true).SetTitleAlign(tview.AlignLeft)
+
+	left := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(users, 0, 1, true).
+		AddItem(roles, 0, 1, false)
+	flex := tview.NewFlex().
+		AddItem(left, 0, 1, true).
+		AddItem(items, 0, 1, false)
+	return &AuthView{Flex: flex, Users: users, Roles: roles, Items: items}
+}
+
+// NewUserForm asks for a password twice, and for a user name when name
+// is empty.
+func (v *View) NewUserForm(name string) *tview.Form {
+	form := tview.NewForm()
+	title := " New user "
+	if name == "" {
+		form.AddInputField("Name", "", 30, nil, nil)
+	} else {
+		title = fmt.Sprintf(" Password for %s ", tview.Escape(name))
+	}
+	form.AddPasswordField("Password", "", 30, '*', nil).
+		AddPasswordField("Repeat", "", 30, '*', nil)
+	form.SetBorder(true).SetTitle(title)
+	return form
+}
+
+func (v *View) NewRoleInput() *tview.InputField {
+	inp := tview.NewInputField()
+	inp.SetBorder(true).SetTitle(" New role ")
+	return inp
+}
+
+// NewPicker lists items to choose one from.
+func (v *View) NewPicker(title string, items []string) *tview.List {
+	list := tview.NewList().ShowSecondaryText(false)
+	for _, it := range items {
+		list.AddItem(tview.Escape(it), "", 0, nil)
+	}
+	list.SetBorder(true).SetTitle(" " + title + " ")
+	return list
+}
+
+// NewPermissionForm edits a role permission: a key, how it selects keys
+// (matches), a range end and the permission type (types).
+func (v *View) NewPermissionForm(title, key, match, rangeEnd, typ string, matches, types []string) *tview.Form {
+	form := tview.NewForm().
+		AddInputField("Key", key, 40, nil, nil).
+		AddDropDown("Match", matches, indexOf(matches, match), nil).
+		AddInputField("Range end", rangeEnd, 40, nil, nil).
+		AddDropDown("Permission", types, indexOf(types, typ), nil)
+	form.SetBorder(true).SetTitle(" " + title + " ")
+	return form
+}
+
+func indexOf(list []string, s string) int {
+	for i, it := range list {
+		if it == s {
+			return i
+		}
+	}
+	return 0
+}
+
 // OpenEditor replaces the Frame with a full-screen editor (hides bottom legend).
 func (v *View) OpenEditor(p tview.Primitive) {
 	editor := tview.NewFlex().
 		SetDirection(tview.FlexRow).
 		AddItem(p, 0, 1, true)
-	v.App.SetRoot(editor, true) // hide frame + legend while editing
+	v.editor = tview.NewPages().AddPage("editor", editor, true, true)
+	v.App.SetRoot(v.editor, true) // hide frame + legend while editing
 	v.App.SetFocus(p)
 }
 
 // CloseEditor restores the normal UI.
 func (v *View) CloseEditor() {
+	v.editor = nil
 	v.App.SetRoot(v.Frame, true)
 	v.App.SetFocus(v.List)
 }
+
+// ActivePages returns the page stack dialogs should be added to: the
+// editor's while it is open, the main one otherwise.
+func (v *View) ActivePages() *tview.Pages {
+	if v.editor != nil {
+		return v.editor
+	}
+	return v.Pages
+}
+
+func (v *View) NewConflictQ(key string) *tview.Modal {
+	conflictQ := tview.NewModal()
+	conflictQ.SetText(fmt.Sprintf("%s was changed by someone else while you were editing it.", key)).
+		SetBackgroundColor(tcell.ColorDarkOrange).
+		AddButtons([]string{"Overwrite", "Reload", "Diff", "Cancel"})
+	return conflictQ
+}
+
+func (v *View) NewDiffView(title string) *tview.TextView {
+	tv := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWordWrap(true)
+	tv.SetBorder(true).SetTitle(" " + title + "  [Esc=Back] ")
+	return tv
+}
+
+// NewKeyReport is a scrollable list of the keys an operation touched.
+func (v *View) NewKeyReport(title string) *tview.TextView {
+	tv := tview.NewTextView().SetWordWrap(true)
+	tv.SetBorder(true).SetTitle(" " + title + "  [Esc=Close] ")
+	return tv
+}